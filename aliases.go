@@ -0,0 +1,52 @@
+package main
+
+import "strings"
+
+// stripRedundantAliases removes aliases equal to the name of the imported
+// package, e.g. fmt "fmt", and returns the imports changed. Imports whose
+// package name cannot be determined keep their alias.
+func (m *impManager) stripRedundantAliases(filePath string) []string {
+	var stripped []string
+	for _, group := range m.groups {
+		for _, imp := range group.models {
+			if imp.localReference == "" || imp.localReference == "_" || imp.localReference == "." {
+				continue
+			}
+			name, ok := packageName(strings.Trim(imp.path, "\""), filePath)
+			if !ok || name != imp.localReference {
+				continue
+			}
+			stripped = append(stripped, imp.string())
+			imp.localReference = ""
+		}
+	}
+	return stripped
+}
+
+// applyCanonicalAliases imports the paths listed in aliases under their
+// required name and returns the package names to rename in the file body.
+// In check mode every violation is reported.
+func (m *impManager) applyCanonicalAliases(aliases map[string]string, filePath string) map[string]string {
+	renames := make(map[string]string)
+	for _, group := range m.groups {
+		for _, imp := range group.models {
+			alias, ok := aliases[strings.Trim(imp.path, "\"")]
+			if !ok || imp.localReference == alias || imp.localReference == "_" || imp.localReference == "." {
+				continue
+			}
+			name, ok := importName(imp, filePath)
+			if !ok {
+				m.warnf("%s must be imported as %s, cannot determine its package name to rename it", imp.path, alias)
+				continue
+			}
+			if *check {
+				m.warnf("%s must be imported as %s", imp.string(), alias)
+			}
+			if name != alias {
+				renames[name] = alias
+			}
+			imp.localReference = alias
+		}
+	}
+	return renames
+}
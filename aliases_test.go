@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProcess_StripAliases(t *testing.T) {
+	resetBoolFlag(t, stripAliases)
+	*stripAliases = true
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "example.com/app"
+	root := t.TempDir()
+	t.Setenv("GOMODCACHE", t.TempDir())
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(root, "vendor", "github.com", "pkg", "errors", "errors.go"), "package errors\n")
+	writeFile(t, filepath.Join(root, "internal", "go-util", "util.go"), "package util\n")
+
+	src := `package main
+
+import (
+	fmt "fmt"
+	rand "math/rand/v2"
+	str "strings"
+	_ "embed"
+	errors "github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+	util "example.com/app/internal/go-util"
+)
+`
+	want := `package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math/rand/v2"
+	str "strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+
+	"example.com/app/internal/go-util"
+)
+`
+	got, err := process([]byte(src), filepath.Join(root, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,43 +1,151 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"golang.org/x/tools/go/packages"
 )
 
+// cacheSchema is bumped whenever the PackageInfo format changes
+const cacheSchema = 2
+
 type PackageInfo struct {
 	Data    map[string]struct{} `json:"data"`
 	Version string              `json:"version"`
+	// Schema, Fingerprint and Checksum guard against stale, foreign or
+	// damaged cache files, see verify
+	Schema      int    `json:"schema"`
+	Fingerprint string `json:"fingerprint"`
+	Checksum    string `json:"checksum"`
+}
+
+var errCacheInvalid = errors.New("invalid standard package cache")
+
+// checksum hashes the package data together with its metadata
+func (info *PackageInfo) checksum() string {
+	pkgs := make([]string, 0, len(info.Data))
+	for p := range info.Data {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%d\x00%s\x00%s\x00", info.Schema, info.Version, info.Fingerprint)
+	for _, p := range pkgs {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// verify checks that a cache file was written by this schema for this
+// toolchain and platform, and was not modified since
+func (info *PackageInfo) verify(version string) error {
+	switch {
+	case info.Schema != cacheSchema:
+		return fmt.Errorf("%w: schema %d, want %d", errCacheInvalid, info.Schema, cacheSchema)
+	case info.Version != version:
+		return fmt.Errorf("%w: version %s, want %s", errCacheInvalid, info.Version, version)
+	case info.Fingerprint != cacheFingerprint():
+		return fmt.Errorf("%w: fingerprint %q, want %q", errCacheInvalid, info.Fingerprint, cacheFingerprint())
+	case info.Checksum != info.checksum():
+		return fmt.Errorf("%w: checksum mismatch", errCacheInvalid)
+	}
+	return nil
+}
+
+// cacheFingerprint identifies the platform the std package set was derived
+// for. The toolchain is already identified by the Go version of the cache;
+// its GOROOT is left out since it can only be found cheaply from the
+// environment, and not at all in -trimpath builds.
+func cacheFingerprint() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
 }
 
+// cacheDirEnv names the environment variable overriding the cache directory
+const cacheDirEnv = "SORTIMPORT_CACHE"
+
 // CacheManager handles version-aware cache operations
 type CacheManager struct {
 	cacheDir string
 	version  string
+	// memoryOnly is set when no cache directory is writable; nothing is
+	// read from or written to disk then
+	memoryOnly bool
 }
 
-// newCacheManager creates a new CacheManager for the current Go version
+// newCacheManager creates a new CacheManager for the current Go version.
+// It uses the first writable cache directory, see cacheDirCandidates, and
+// degrades to an in-memory-only cache when none is writable.
 func newCacheManager() (*CacheManager, error) {
-	homedir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
-	cacheDir := filepath.Join(homedir, ".cache", "sortimport")
 	version := runtime.Version()
 
+	for _, cacheDir := range cacheDirCandidates() {
+		if err := checkWritableDir(cacheDir); err != nil {
+			warnf("cache directory %s is not writable: %v", cacheDir, err)
+			continue
+		}
+		return &CacheManager{
+			cacheDir: cacheDir,
+			version:  version,
+		}, nil
+	}
+
+	warnf("no writable cache directory, caching in memory only")
 	return &CacheManager{
-		cacheDir: cacheDir,
-		version:  version,
+		version:    version,
+		memoryOnly: true,
 	}, nil
 }
 
+// cacheDirCandidates lists the cache directories to try: the -cache-dir
+// flag or SORTIMPORT_CACHE when set, otherwise the platform user cache
+// directory (honouring XDG_CACHE_HOME) with ~/.cache as a fallback.
+func cacheDirCandidates() []string {
+	if *cacheDirFlag != "" {
+		return []string{*cacheDirFlag}
+	}
+	if dir := os.Getenv(cacheDirEnv); dir != "" {
+		return []string{dir}
+	}
+
+	var candidates []string
+	if dir, err := os.UserCacheDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "sortimport"))
+	}
+	if homedir, err := os.UserHomeDir(); err == nil {
+		dir := filepath.Join(homedir, ".cache", "sortimport")
+		if len(candidates) == 0 || candidates[0] != dir {
+			candidates = append(candidates, dir)
+		}
+	}
+	return candidates
+}
+
+// checkWritableDir creates dir if needed and checks files can be created in it.
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
+
 // getCacheFile returns the version-specific cache file path
 func (c *CacheManager) getCacheFile() string {
 	// Sanitize version for filename (replace spaces and special chars)
@@ -47,6 +155,9 @@ func (c *CacheManager) getCacheFile() string {
 
 // read loads the cache for the current Go version
 func (c *CacheManager) read() (*PackageInfo, error) {
+	if c.memoryOnly {
+		return nil, os.ErrNotExist
+	}
 	cacheFile := c.getCacheFile()
 
 	if _, err := os.Stat(cacheFile); os.IsNotExist(err) {
@@ -60,15 +171,25 @@ func (c *CacheManager) read() (*PackageInfo, error) {
 
 	var info PackageInfo
 	if err := json.Unmarshal(bs, &info); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCacheInvalid, err)
+	}
+	if err := info.verify(c.version); err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("load standard package cache from %s\n", cacheFile)
+	// Record the use so that `cache prune` keeps this version
+	now := time.Now()
+	_ = os.Chtimes(cacheFile, now, now)
+
+	debugf("load standard package cache from %s", cacheFile)
 	return &info, nil
 }
 
 // write saves the cache for the current Go version
 func (c *CacheManager) write(packages map[string]struct{}) error {
+	if c.memoryOnly {
+		return nil
+	}
 	// Ensure cache directory exists
 	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
 		return err
@@ -76,38 +197,41 @@ func (c *CacheManager) write(packages map[string]struct{}) error {
 
 	cacheFile := c.getCacheFile()
 	info := PackageInfo{
-		Data:    make(map[string]struct{}),
-		Version: c.version,
+		Data:        make(map[string]struct{}),
+		Version:     c.version,
+		Schema:      cacheSchema,
+		Fingerprint: cacheFingerprint(),
 	}
 	for k, v := range packages {
 		info.Data[k] = v
 	}
+	info.Checksum = info.checksum()
 
 	bs, err := json.Marshal(info)
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(cacheFile, bs, 0644); err != nil {
+	unlock, err := lockFile(cacheFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := atomicWriteFile(cacheFile, bs, 0644); err != nil {
 		return err
 	}
 
-	fmt.Printf("write standard package cache to %s\n", cacheFile)
+	debugf("write standard package cache to %s", cacheFile)
 	return nil
 }
 
 // update forces a cache refresh for the current Go version
 func (c *CacheManager) update() error {
-	pkgs, err := packages.Load(nil, "std")
+	packages, err := loadToolchainStdPackages()
 	if err != nil {
 		return err
 	}
 
-	packages := make(map[string]struct{})
-	for _, p := range pkgs {
-		packages[p.PkgPath] = struct{}{}
-	}
-
 	return c.write(packages)
 }
 
@@ -118,21 +242,19 @@ func (c *CacheManager) loadOrFetch() (map[string]struct{}, error) {
 	if err == nil && info != nil {
 		return info.Data, nil
 	}
+	if errors.Is(err, errCacheInvalid) {
+		warnf("rebuilding standard package cache: %v", err)
+	}
 
 	// Cache miss or error - fetch fresh data
-	pkgs, err := packages.Load(nil, "std")
+	packages, err := fetchStandardPackages(c.version)
 	if err != nil {
 		return nil, err
 	}
 
-	packages := make(map[string]struct{})
-	for _, p := range pkgs {
-		packages[p.PkgPath] = struct{}{}
-	}
-
 	// Write to cache
 	if err := c.write(packages); err != nil {
-		log.Printf("warning: failed to write cache: %v", err)
+		warnf("failed to write cache: %v", err)
 	}
 
 	return packages, nil
@@ -145,7 +267,7 @@ func loadStandardPackages() error {
 		var err error
 		cacheManager, err = newCacheManager()
 		if err != nil {
-			log.Printf("warning: failed to initialize cache manager: %v\n", err)
+			warnf("failed to initialize cache manager: %v", err)
 		}
 	}
 
@@ -162,18 +284,141 @@ func loadStandardPackages() error {
 	}
 
 	// Fallback: load directly without cache
-	pkgs, err := packages.Load(nil, "std")
+	pkgs, err := fetchStandardPackages(runtime.Version())
 	if err != nil {
 		return err
 	}
-	for _, p := range pkgs {
-		standardPackages[p.PkgPath] = struct{}{}
+	for k, v := range pkgs {
+		standardPackages[k] = v
 	}
 	return nil
 }
 
+// fetchStandardPackages returns the std packages of the given Go version,
+// preferring the embedded tables and only asking the go command for
+// versions they do not know
+func fetchStandardPackages(version string) (map[string]struct{}, error) {
+	if pkgs, ok := embeddedStdPackages(version); ok {
+		return pkgs, nil
+	}
+	return loadToolchainStdPackages()
+}
+
+// loadToolchainStdPackages returns the std packages of the installed
+// toolchain, walking GOROOT and only falling back to `go list` (slow)
+// when that fails
+func loadToolchainStdPackages() (map[string]struct{}, error) {
+	if goroot, err := findGoroot(); err == nil {
+		pkgs, err := walkGorootPackages(goroot)
+		if err == nil && len(pkgs) > 0 {
+			return pkgs, nil
+		}
+		warnf("failed to walk GOROOT %s: %v", goroot, err)
+	}
+
+	pkgs, err := packages.Load(nil, "std")
+	if err != nil {
+		return nil, err
+	}
+	packages := make(map[string]struct{})
+	for _, p := range pkgs {
+		packages[p.PkgPath] = struct{}{}
+	}
+	return packages, nil
+}
+
+// versionedStandardPackages memoizes std sets of module Go versions
+var versionedStandardPackages = make(map[string]map[string]struct{})
+
+// standardPackagesFor returns the std packages of the given Go version from
+// the cache or the embedded tables, memoized per version. It falls back to
+// the default standardPackages when the version is unknown.
+func standardPackagesFor(version string) map[string]struct{} {
+	if version == "" {
+		return standardPackages
+	}
+	if pkgs, ok := versionedStandardPackages[version]; ok {
+		return pkgs
+	}
+
+	pkgs := standardPackages
+	if embedded, ok := embeddedStdPackages(version); ok {
+		pkgs = embedded
+	}
+	if cacheManager != nil {
+		versioned := &CacheManager{cacheDir: cacheManager.cacheDir, version: version, memoryOnly: cacheManager.memoryOnly}
+		if info, err := versioned.read(); err == nil && info != nil {
+			pkgs = info.Data
+		}
+	}
+	versionedStandardPackages[version] = pkgs
+	return pkgs
+}
+
 // isStandardPackage checks if a package string is included in the standardPackages map
 func isStandardPackage(pkg string) bool {
-	_, ok := standardPackages[pkg]
+	return isStandardPackageIn(standardPackages, pkg)
+}
+
+// isStandardPackageIn checks if a package string is included in the given std set
+func isStandardPackageIn(std map[string]struct{}, pkg string) bool {
+	_, ok := std[pkg]
 	return ok
 }
+
+// lockStaleAfter is the age after which a lock file is considered left
+// behind by a crashed run
+const lockStaleAfter = 10 * time.Second
+
+// lockFile takes an exclusive lock for path by creating path+".lock",
+// waiting for other holders and breaking stale locks. The returned
+// function releases the lock.
+func lockFile(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(2 * lockStaleAfter)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(lock); statErr == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			_ = os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lock)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// atomicWriteFile writes data to a temporary file next to path and renames
+// it into place, so readers never observe a partially written file
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
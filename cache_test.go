@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func Test_loadStandardPackages(t *testing.T) {
@@ -283,3 +289,211 @@ func TestIsStandardPackage(t *testing.T) {
 		t.Error("empty string should not be standard")
 	}
 }
+
+func TestCacheDirCandidates(t *testing.T) {
+	resetStringFlag(t, cacheDirFlag)
+
+	t.Setenv(cacheDirEnv, "")
+	t.Setenv("XDG_CACHE_HOME", "/xdg/cache")
+	if runtime.GOOS == "linux" {
+		if got := cacheDirCandidates(); got[0] != filepath.Join("/xdg/cache", "sortimport") {
+			t.Errorf("expected XDG_CACHE_HOME to be honoured, got %v", got)
+		}
+	}
+
+	t.Setenv(cacheDirEnv, "/env/cache")
+	if got := cacheDirCandidates(); len(got) != 1 || got[0] != "/env/cache" {
+		t.Errorf("expected %s override, got %v", cacheDirEnv, got)
+	}
+
+	*cacheDirFlag = "/flag/cache"
+	if got := cacheDirCandidates(); len(got) != 1 || got[0] != "/flag/cache" {
+		t.Errorf("expected -cache-dir override, got %v", got)
+	}
+}
+
+func TestCacheManager_Override(t *testing.T) {
+	resetStringFlag(t, cacheDirFlag)
+	dir := filepath.Join(t.TempDir(), "custom")
+	t.Setenv(cacheDirEnv, dir)
+
+	cm, err := newCacheManager()
+	if err != nil {
+		t.Fatalf("newCacheManager: %v", err)
+	}
+	if cm.cacheDir != dir || cm.memoryOnly {
+		t.Errorf("expected cache in %s, got %+v", dir, cm)
+	}
+}
+
+func TestCacheManager_MemoryOnly(t *testing.T) {
+	resetStringFlag(t, cacheDirFlag)
+	// A directory below a regular file can never be created.
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(cacheDirEnv, filepath.Join(blocker, "sortimport"))
+
+	cm, err := newCacheManager()
+	if err != nil {
+		t.Fatalf("newCacheManager: %v", err)
+	}
+	if !cm.memoryOnly {
+		t.Fatalf("expected in-memory mode, got %+v", cm)
+	}
+	if err := cm.write(map[string]struct{}{"fmt": {}}); err != nil {
+		t.Errorf("write should be a no-op, got: %v", err)
+	}
+	if _, err := cm.read(); err == nil {
+		t.Error("read should miss in in-memory mode")
+	}
+	got, err := cm.loadOrFetch()
+	if err != nil {
+		t.Fatalf("loadOrFetch: %v", err)
+	}
+	if _, ok := got["fmt"]; !ok {
+		t.Error("expected std packages without a cache directory")
+	}
+}
+
+func TestCacheManager_ReadRejectsDamagedCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(info map[string]any) []byte
+	}{
+		{"truncated", func(info map[string]any) []byte {
+			bs, _ := json.Marshal(info)
+			return bs[:len(bs)/2]
+		}},
+		{"hand edited", func(info map[string]any) []byte {
+			info["data"].(map[string]any)["github.com/not/std"] = map[string]any{}
+			bs, _ := json.Marshal(info)
+			return bs
+		}},
+		{"old schema", func(info map[string]any) []byte {
+			info["schema"] = 1
+			bs, _ := json.Marshal(info)
+			return bs
+		}},
+		{"other platform", func(info map[string]any) []byte {
+			info["fingerprint"] = "/other/goroot;plan9/386"
+			bs, _ := json.Marshal(info)
+			return bs
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &CacheManager{cacheDir: t.TempDir(), version: runtime.Version()}
+			if err := cm.write(map[string]struct{}{"fmt": {}}); err != nil {
+				t.Fatal(err)
+			}
+			bs, err := os.ReadFile(cm.getCacheFile())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var info map[string]any
+			if err := json.Unmarshal(bs, &info); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(cm.getCacheFile(), tt.damage(info), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := cm.read(); !errors.Is(err, errCacheInvalid) {
+				t.Fatalf("expected errCacheInvalid, got: %v", err)
+			}
+
+			// loadOrFetch rebuilds the cache from scratch.
+			got, err := cm.loadOrFetch()
+			if err != nil {
+				t.Fatalf("loadOrFetch: %v", err)
+			}
+			if _, ok := got["github.com/not/std"]; ok {
+				t.Error("damaged data must not be used")
+			}
+			if _, err := cm.read(); err != nil {
+				t.Errorf("expected rebuilt cache to be valid, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCacheFingerprint(t *testing.T) {
+	want := runtime.GOOS + "/" + runtime.GOARCH
+	// neither the go command nor the GOROOT of the build are needed
+	t.Setenv("PATH", "")
+	for _, goroot := range []string{"", "/opt/custom/go"} {
+		t.Setenv("GOROOT", goroot)
+		if got := cacheFingerprint(); got != want {
+			t.Errorf("GOROOT=%q: cacheFingerprint() = %q, want %q", goroot, got, want)
+		}
+	}
+}
+
+func TestCacheManager_ConcurrentWrites(t *testing.T) {
+	cm := &CacheManager{cacheDir: t.TempDir(), version: "go1.21.0"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cm.write(map[string]struct{}{"fmt": {}, "os": {}}); err != nil {
+				t.Errorf("write: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := cm.read(); err != nil {
+		t.Errorf("expected a valid cache after concurrent writes, got: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(cm.cacheDir, "*.tmp-*")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+	if _, err := os.Stat(cm.getCacheFile() + ".lock"); !os.IsNotExist(err) {
+		t.Error("lock file left behind")
+	}
+}
+
+func TestLockFile_BreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	stale := time.Now().Add(-2 * lockStaleAfter)
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path+".lock", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile: %v", err)
+	}
+	unlock()
+}
+
+func TestCacheManager_NoStdout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	cm := &CacheManager{cacheDir: t.TempDir(), version: "go1.21.0"}
+	if err := cm.write(map[string]struct{}{"fmt": {}}); err != nil {
+		t.Errorf("write: %v", err)
+	}
+	if _, err := cm.read(); err != nil {
+		t.Errorf("read: %v", err)
+	}
+
+	os.Stdout = stdout
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+	if len(out) != 0 {
+		t.Errorf("expected nothing on stdout, got: %q", out)
+	}
+}
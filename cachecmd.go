@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// cacheEntry describes one file in the cache directory
+type cacheEntry struct {
+	name    string // Go version, or "runs/<project>" for run caches
+	path    string
+	size    int64
+	modTime time.Time // last use, see CacheManager.read
+}
+
+// entries lists the std package caches and run caches, sorted by name.
+func (c *CacheManager) entries() ([]cacheEntry, error) {
+	if c.memoryOnly {
+		return nil, nil
+	}
+	var entries []cacheEntry
+	for _, pattern := range []string{"*.json", filepath.Join("runs", "*.json")} {
+		matches, err := filepath.Glob(filepath.Join(c.cacheDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			rel, _ := filepath.Rel(c.cacheDir, path)
+			entries = append(entries, cacheEntry{
+				name:    filepath.ToSlash(strings.TrimSuffix(rel, ".json")),
+				path:    path,
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+// prune removes the cache entries not used since before.
+func (c *CacheManager) prune(before time.Time) ([]cacheEntry, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	var removed []cacheEntry
+	for _, e := range entries {
+		if !e.modTime.Before(before) {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// ownedCachePatterns match the files sortimport creates in the cache
+// directory: caches, locks and leftover temporary files.
+var ownedCachePatterns = []string{
+	"*.json", "*.lock", "*.tmp-*",
+	filepath.Join("runs", "*.json"), filepath.Join("runs", "*.lock"), filepath.Join("runs", "*.tmp-*"),
+}
+
+// clear deletes the files sortimport owns in the cache directory and
+// returns how many were removed. The directory itself and anything else in
+// it are left alone, since it may be shared through -cache-dir or
+// $SORTIMPORT_CACHE.
+func (c *CacheManager) clear() (int, error) {
+	if c.memoryOnly {
+		return 0, nil
+	}
+	removed := 0
+	for _, pattern := range ownedCachePatterns {
+		matches, err := filepath.Glob(filepath.Join(c.cacheDir, pattern))
+		if err != nil {
+			return removed, err
+		}
+		for _, path := range matches {
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	// only drop runs/ once empty
+	_ = os.Remove(filepath.Join(c.cacheDir, "runs"))
+	return removed, nil
+}
+
+// cacheFlagSet returns a flag set for the cache subcommand name that accepts
+// -cache-dir, so the directory can be given before or after the command.
+func cacheFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(cacheDirFlag, "cache-dir", *cacheDirFlag, "cache directory (default $"+cacheDirEnv+", else the user cache directory)")
+	return fs
+}
+
+// cacheMain implements the cache subcommand.
+func cacheMain(args []string) error {
+	usage := func() {
+		_, _ = fmt.Fprintf(os.Stderr, "usage: sortimport cache [-cache-dir dir] list|info|prune [-days N]|clear\n")
+	}
+	fs := cacheFlagSet("cache")
+	fs.Usage = usage
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		usage()
+		return errors.New("missing cache command")
+	}
+
+	command := fs.Arg(0)
+	sub := cacheFlagSet("cache " + command)
+	days := 30
+	if command == "prune" {
+		sub.IntVar(&days, "days", days, "remove caches not used in this many days")
+	}
+	if err := sub.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	cm, err := newCacheManager()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		return cacheList(cm, os.Stdout, time.Now())
+	case "info":
+		return cacheInfo(cm, os.Stdout)
+	case "prune":
+		removed, err := cm.prune(time.Now().AddDate(0, 0, -days))
+		for _, e := range removed {
+			fmt.Printf("removed %s\n", e.name)
+		}
+		return err
+	case "clear":
+		removed, err := cm.clear()
+		fmt.Printf("removed %d files from %s\n", removed, cm.cacheDir)
+		return err
+	default:
+		usage()
+		return fmt.Errorf("unknown cache command %q", command)
+	}
+}
+
+// cacheList prints one line per cache entry with its size and age.
+func cacheList(cm *CacheManager, out io.Writer, now time.Time) error {
+	entries, err := cm.entries()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tSIZE\tLAST USED")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s ago\n", e.name, formatSize(e.size), formatAge(now.Sub(e.modTime)))
+	}
+	return tw.Flush()
+}
+
+// cacheInfo prints a summary of the cache directory.
+func cacheInfo(cm *CacheManager, out io.Writer) error {
+	entries, err := cm.entries()
+	if err != nil {
+		return err
+	}
+	var versions, runs int
+	var total int64
+	for _, e := range entries {
+		if strings.HasPrefix(e.name, "runs/") {
+			runs++
+		} else {
+			versions++
+		}
+		total += e.size
+	}
+	current, dir := "no", cm.cacheDir
+	if cm.memoryOnly {
+		dir = "(none, in-memory only)"
+	} else if _, err := os.Stat(cm.getCacheFile()); err == nil {
+		current = "yes"
+	}
+	_, _ = fmt.Fprintf(out, "directory:       %s\n", dir)
+	_, _ = fmt.Fprintf(out, "go version:      %s (cached: %s)\n", cm.version, current)
+	_, _ = fmt.Fprintf(out, "std caches:      %d\n", versions)
+	_, _ = fmt.Fprintf(out, "run caches:      %d\n", runs)
+	_, _ = fmt.Fprintf(out, "total size:      %s\n", formatSize(total))
+	return nil
+}
+
+// formatSize renders a byte count in human readable units.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatAge renders a duration in its largest whole unit.
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	default:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	}
+}
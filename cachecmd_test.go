@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T) *CacheManager {
+	t.Helper()
+	cm := &CacheManager{cacheDir: t.TempDir(), version: "go1.22.0"}
+	for _, version := range []string{"go1.21.0", "go1.22.0"} {
+		c := &CacheManager{cacheDir: cm.cacheDir, version: version}
+		if err := c.write(map[string]struct{}{"fmt": {}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rc := openRunCache(cm.cacheDir, t.TempDir(), "key")
+	rc.dirty = true
+	if err := rc.save(); err != nil {
+		t.Fatal(err)
+	}
+	return cm
+}
+
+func TestCacheManager_Entries(t *testing.T) {
+	cm := newTestCache(t)
+	entries, err := cm.entries()
+	if err != nil {
+		t.Fatalf("entries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %v", entries)
+	}
+	if entries[0].name != "go1.21.0" || entries[1].name != "go1.22.0" {
+		t.Errorf("unexpected version entries: %v", entries)
+	}
+	if !strings.HasPrefix(entries[2].name, "runs/") {
+		t.Errorf("expected run cache entry, got %s", entries[2].name)
+	}
+	for _, e := range entries {
+		if e.size == 0 {
+			t.Errorf("expected non-zero size for %s", e.name)
+		}
+	}
+}
+
+func TestCacheManager_Prune(t *testing.T) {
+	cm := newTestCache(t)
+	old := time.Now().AddDate(0, 0, -40)
+	oldFile := (&CacheManager{cacheDir: cm.cacheDir, version: "go1.21.0"}).getCacheFile()
+	if err := os.Chtimes(oldFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := cm.prune(time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if len(removed) != 1 || removed[0].name != "go1.21.0" {
+		t.Errorf("removed = %v, want only go1.21.0", removed)
+	}
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Error("expected pruned cache file to be gone")
+	}
+	if _, err := os.Stat(cm.getCacheFile()); err != nil {
+		t.Errorf("recently used cache should be kept: %v", err)
+	}
+}
+
+func TestCacheManager_ReadTouches(t *testing.T) {
+	cm := newTestCache(t)
+	old := time.Now().AddDate(0, 0, -40)
+	if err := os.Chtimes(cm.getCacheFile(), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.read(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(cm.getCacheFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ModTime().Before(time.Now().Add(-time.Hour)) {
+		t.Errorf("read should mark the cache as used, mtime %v", info.ModTime())
+	}
+}
+
+func TestCacheManager_Clear(t *testing.T) {
+	cm := newTestCache(t)
+	owned := []string{"go1.22.0.json.lock", "go1.22.0.json.tmp-123", filepath.Join("runs", "x.json.tmp-456")}
+	foreign := []string{"notes.txt", filepath.Join("other", "keep.json")}
+	for _, name := range append(owned, foreign...) {
+		path := filepath.Join(cm.cacheDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := cm.clear()
+	if err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if removed != 3+len(owned) {
+		t.Errorf("expected %d files removed, got %d", 3+len(owned), removed)
+	}
+	for _, name := range owned {
+		if _, err := os.Stat(filepath.Join(cm.cacheDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, stat err = %v", name, err)
+		}
+	}
+	for _, name := range foreign {
+		if _, err := os.Stat(filepath.Join(cm.cacheDir, name)); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+	entries, err := cm.entries()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries after clear, got %v, %v", entries, err)
+	}
+}
+
+func TestCacheList(t *testing.T) {
+	cm := newTestCache(t)
+	var out bytes.Buffer
+	if err := cacheList(cm, &out, time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatalf("cacheList: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"NAME", "go1.21.0", "go1.22.0", "runs/", "2d ago"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:           "0 B",
+		1023:        "1023 B",
+		1024:        "1.0 KiB",
+		1536:        "1.5 KiB",
+		1024 * 1024: "1.0 MiB",
+	}
+	for n, want := range tests {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCacheMain_CacheDir(t *testing.T) {
+	defer func(dir string) { *cacheDirFlag = dir }(*cacheDirFlag)
+
+	for _, args := range [][]string{
+		{"-cache-dir", "DIR", "cache", "clear"},
+		{"cache", "-cache-dir", "DIR", "clear"},
+		{"cache", "clear", "-cache-dir", "DIR"},
+	} {
+		*cacheDirFlag = ""
+		cm := newTestCache(t)
+		for i, arg := range args {
+			if arg == "DIR" {
+				args[i] = cm.cacheDir
+			}
+		}
+
+		cmd, rest, ok := findSubcommand(args)
+		if !ok {
+			t.Fatalf("%v: expected a subcommand", args)
+		}
+		if err := cmd(rest); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if entries, err := cm.entries(); err != nil || len(entries) != 0 {
+			t.Errorf("%v: expected cache in %s to be cleared, got %v, %v", args, cm.cacheDir, entries, err)
+		}
+	}
+}
+
+func TestFindSubcommand_NotSubcommand(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"./..."},
+		{"-v", "cache"},
+		{"-cache-dir", "x", "./pkg"},
+	} {
+		if _, _, ok := findSubcommand(args); ok {
+			t.Errorf("%v: unexpected subcommand", args)
+		}
+	}
+}
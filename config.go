@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+)
+
+// configFileName is looked up from the working directory upwards when
+// -config is not set
+const configFileName = ".sortimport.json"
+
+// Config is the optional project configuration
+type Config struct {
+	// Aliases maps import paths to the name they must be imported as
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Rules deny imports, see Rule
+	Rules []Rule `json:"rules,omitempty"`
+
+	dir string // directory of the config file, rule globs are relative to it
+}
+
+// config is the configuration of the current run, empty without a config file
+var config = &Config{}
+
+// findConfig returns the nearest config file in dir or its parents, or "".
+func findConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, configFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadConfig reads and validates a config file. Unknown fields are
+// rejected so that typos do not go unnoticed.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for importPath, alias := range c.Aliases {
+		if !token.IsIdentifier(alias) || alias == "_" {
+			return nil, fmt.Errorf("%s: alias %q of %s is not a valid package name", path, alias, importPath)
+		}
+	}
+	for _, rule := range c.Rules {
+		for _, glob := range rule.Files {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("%s: file glob %q: %w", path, glob, err)
+			}
+		}
+		for _, denied := range rule.Deny {
+			if _, err := filepath.Match(denied.Path, ""); err != nil {
+				return nil, fmt.Errorf("%s: denied import %q: %w", path, denied.Path, err)
+			}
+		}
+	}
+	if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+		c.dir = dir
+	}
+	return &c, nil
+}
+
+// setupConfig loads the config file given with -config, else the nearest
+// one found from the working directory.
+func setupConfig(path string) error {
+	if path == "" {
+		path = findConfig(".")
+		if path == "" {
+			return nil
+		}
+	}
+	c, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	debugf("using config %s", path)
+	config = c
+	return nil
+}
+
+// key identifies the configuration for the run cache
+func (c *Config) key() string {
+	data, _ := json.Marshal(c)
+	return c.dir + string(data)
+}
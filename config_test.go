@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindConfig(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, configFileName), "{}")
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := findConfig(nested); got != filepath.Join(root, configFileName) {
+		t.Errorf("findConfig = %q", got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name, content, wantErr string
+	}{
+		{"valid", `{"aliases": {"k8s.io/api/core/v1": "corev1"}}`, ""},
+		{"unknown field", `{"alias": {}}`, "unknown field"},
+		{"invalid deny pattern", `{"rules": [{"deny": [{"path": "a/["}]}]}`, "syntax error in pattern"},
+		{"invalid alias", `{"aliases": {"k8s.io/api/core/v1": "core-v1"}}`, "not a valid package name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), configFileName)
+			writeFile(t, path, tt.content)
+			_, err := loadConfig(path)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("loadConfig error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func setConfig(t *testing.T, c *Config) {
+	t.Helper()
+	prev := config
+	t.Cleanup(func() { config = prev })
+	config = c
+}
+
+func TestProcess_CanonicalAliases(t *testing.T) {
+	setConfig(t, &Config{Aliases: map[string]string{
+		"k8s.io/api/core/v1":                   "corev1",
+		"k8s.io/apimachinery/pkg/apis/meta/v1": "metav1",
+		"strings":                              "str",
+	}})
+	src := `package main
+
+import (
+	"strings"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func main() {
+	var v1Pod v1.Pod
+	_ = strings.TrimSpace(v1Pod.Name)
+	_ = metav1.Now()
+}
+`
+	want := `package main
+
+import (
+	str "strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func main() {
+	var v1Pod corev1.Pod
+	_ = str.TrimSpace(v1Pod.Name)
+	_ = metav1.Now()
+}
+`
+	got, stats, err := processWithStats([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if len(stats.warnings) != 0 {
+		t.Errorf("unexpected warnings outside check mode: %q", stats.warnings)
+	}
+
+	resetBoolFlag(t, check)
+	*check = true
+	_, stats, err = processWithStats([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantWarnings := []string{`"strings" must be imported as str`, `v1 "k8s.io/api/core/v1" must be imported as corev1`}
+	if !reflect.DeepEqual(stats.warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", stats.warnings, wantWarnings)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/dstutil"
+)
+
+// errBodyRewrite is returned by the import-only paths when the import
+// changes also need usages renamed in the file body
+var errBodyRewrite = errors.New("imports need usages renamed in the file body")
+
+// dedupe drops exact duplicate imports. Imports of one path under several
+// names are reported; with merge they are reduced to the first name in
+// sort order, and the names to rewrite in the file body are returned.
+// Blank and dot imports are never merged.
+func (m *impManager) dedupe(filePath string, merge bool) map[string]string {
+	renames := make(map[string]string)
+	for _, group := range m.groups {
+		seen := make(map[impModel]bool)
+		names := make(map[string][]*impModel) // path -> imports under a name
+		kept := group.models[:0]
+		for _, imp := range group.models {
+			if seen[*imp] {
+				debugf("%s: removed duplicate import %s", filePath, imp.string())
+				continue
+			}
+			seen[*imp] = true
+			kept = append(kept, imp)
+			if imp.localReference != "_" && imp.localReference != "." {
+				names[imp.path] = append(names[imp.path], imp)
+			}
+		}
+		group.models = kept
+
+		for path, imps := range names {
+			if len(imps) < 2 {
+				continue
+			}
+			sort.Slice(imps, func(i, j int) bool {
+				return imps[i].localReference < imps[j].localReference
+			})
+			refs := make([]string, 0, len(imps))
+			for _, imp := range imps {
+				name, ok := importName(imp, filePath)
+				if !ok {
+					name = ""
+				}
+				refs = append(refs, name)
+			}
+			if !merge || slices.Contains(refs, "") {
+				m.warnf("%s is imported %d times, as %s", path, len(imps), strings.Join(importRefs(imps), ", "))
+				continue
+			}
+			for _, name := range refs[1:] {
+				if name != refs[0] {
+					renames[name] = refs[0]
+				}
+			}
+			for _, imp := range imps[1:] {
+				group.models = slices.DeleteFunc(group.models, func(m *impModel) bool { return m == imp })
+			}
+			debugf("%s: merged the imports of %s into %s", filePath, path, imps[0].string())
+		}
+	}
+	return renames
+}
+
+// importRefs lists how imports are written, e.g. `APA "x"`
+func importRefs(imps []*impModel) []string {
+	refs := make([]string, 0, len(imps))
+	for _, imp := range imps {
+		refs = append(refs, imp.string())
+	}
+	return refs
+}
+
+// renamePackageRefs rewrites the qualified identifiers pkg.Name of node
+// whose package name is in renames. Identifiers declared in the file are
+// left alone.
+func renamePackageRefs(node *dst.File, renames map[string]string) {
+	dstutil.Apply(node, func(cr *dstutil.Cursor) bool {
+		sel, ok := cr.Node().(*dst.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := sel.X.(*dst.Ident); ok && ident.Obj == nil {
+			if name, ok := renames[ident.Name]; ok {
+				ident.Name = name
+			}
+		}
+		return true
+	}, nil)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcess_Dedupe(t *testing.T) {
+	src := `package main
+
+import (
+	"fmt"
+	_ "embed"
+)
+
+import "fmt"
+import _ "embed"
+
+func main() { fmt.Println() }
+`
+	want := `package main
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+func main() { fmt.Println() }
+`
+	got, err := process([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProcess_SameImportDifferentNames(t *testing.T) {
+	src := `package main
+
+import (
+	APZ "errors"
+	"fmt"
+)
+
+import APA "errors"
+
+func main() { fmt.Println(APA.New("a"), APZ.New("z")) }
+`
+	t.Run("report", func(t *testing.T) {
+		got, stats, err := processWithStats([]byte(src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), `APA "errors"`) || !strings.Contains(string(got), `APZ "errors"`) {
+			t.Errorf("both imports should be kept:\n%s", got)
+		}
+		want := []string{`"errors" is imported 2 times, as APA "errors", APZ "errors"`}
+		if !reflect.DeepEqual(stats.warnings, want) {
+			t.Errorf("warnings = %q, want %q", stats.warnings, want)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		resetBoolFlag(t, mergeAliases)
+		*mergeAliases = true
+		want := `package main
+
+import (
+	APA "errors"
+	"fmt"
+)
+
+func main() { fmt.Println(APA.New("a"), APA.New("z")) }
+`
+		got, err := process([]byte(src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("merge into package name", func(t *testing.T) {
+		resetBoolFlag(t, mergeAliases)
+		*mergeAliases = true
+		src := "package main\n\nimport (\n\t\"errors\"\n\tE \"errors\"\n)\n\nvar _, _ = errors.New, E.New\n"
+		want := "package main\n\nimport (\n\t\"errors\"\n)\n\nvar _, _ = errors.New, errors.New\n"
+		got, err := process([]byte(src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestProcess_MergeAliasesPrune(t *testing.T) {
+	resetBoolFlag(t, mergeAliases)
+	*mergeAliases = true
+	resetBoolFlag(t, prune)
+	*prune = true
+	src := `package main
+
+import (
+	APA "example.com/a"
+	APZ "example.com/a"
+)
+
+var _ = APZ.Y
+`
+	want := `package main
+
+import (
+	APA "example.com/a"
+)
+
+var _ = APA.Y
+`
+	got, err := process([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+//go:build ignore
+
+// gen_stdlib generates std_packages.txt, the embedded table of standard
+// packages and the Go release that introduced each of them, and
+// std_symbols.txt, the exported package-level names of each of them.
+//
+// Releases are taken from the x/tools std symbol manifest (a package appears
+// with its oldest symbol); packages of the running toolchain that are missing
+// from the manifest are added with the toolchain's release.
+//
+//	go run gen_stdlib.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// overrides fixes packages without exported symbols, which the manifest omits.
+var overrides = map[string]int{
+	"runtime/race": 1,
+	"time/tzdata":  15,
+}
+
+var minorRe = regexp.MustCompile(`^go1\.(\d+)`)
+
+func main() {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "golang.org/x/tools").Output()
+	if err != nil {
+		log.Fatalf("locate x/tools: %v", err)
+	}
+	manifest := filepath.Join(strings.TrimSpace(string(out)), "internal", "stdlib", "manifest.go")
+	since, symbols, err := parseManifest(manifest)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m := minorRe.FindStringSubmatch(runtime.Version())
+	if m == nil {
+		log.Fatalf("cannot generate with toolchain %s", runtime.Version())
+	}
+	current, _ := strconv.Atoi(m[1])
+
+	pkgs, err := packages.Load(nil, "std")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range pkgs {
+		if _, ok := since[p.PkgPath]; ok || !isPublic(p.PkgPath) {
+			continue
+		}
+		since[p.PkgPath] = current
+	}
+	for p, v := range overrides {
+		since[p] = v
+	}
+
+	paths := make([]string, 0, len(since))
+	for p := range since {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Code generated by gen_stdlib.go. DO NOT EDIT.\n")
+	fmt.Fprintf(&buf, "# max go1.%d\n", current)
+	fmt.Fprintf(&buf, "# <import path> <minor Go release that introduced it>\n")
+	for _, p := range paths {
+		fmt.Fprintf(&buf, "%s %d\n", p, since[p])
+	}
+	if err := os.WriteFile("std_packages.txt", buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	buf.Reset()
+	fmt.Fprintf(&buf, "# Code generated by gen_stdlib.go. DO NOT EDIT.\n")
+	fmt.Fprintf(&buf, "# <import path> <exported package-level names>\n")
+	for _, p := range paths {
+		if len(symbols[p]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s %s\n", p, strings.Join(symbols[p], " "))
+	}
+	if err := os.WriteFile("std_symbols.txt", buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseManifest returns the oldest symbol release of every manifest package
+// and its package-level symbols (no methods or fields), sorted.
+func parseManifest(path string) (map[string]int, map[string][]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	since := make(map[string]int)
+	symbols := make(map[string][]string)
+	ast.Inspect(f, func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+		key, ok := kv.Key.(*ast.BasicLit)
+		if !ok || key.Kind != token.STRING {
+			return true
+		}
+		syms, ok := kv.Value.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		pkg, _ := strconv.Unquote(key.Value)
+		oldest := -1
+		for _, elt := range syms.Elts {
+			sym, ok := elt.(*ast.CompositeLit)
+			if !ok || len(sym.Elts) < 3 {
+				continue
+			}
+			lit, ok := sym.Elts[2].(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+			v, _ := strconv.Atoi(lit.Value)
+			if oldest < 0 || v < oldest {
+				oldest = v
+			}
+			name, ok := sym.Elts[0].(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+			if s, _ := strconv.Unquote(name.Value); !strings.ContainsAny(s, ".()") {
+				symbols[pkg] = append(symbols[pkg], s)
+			}
+		}
+		sort.Strings(symbols[pkg])
+		if oldest < 0 {
+			oldest = 0
+		}
+		since[pkg] = oldest
+		return false
+	})
+	return since, symbols, nil
+}
+
+// isPublic reports whether a std package can be imported by user code.
+func isPublic(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "internal" || elem == "vendor" {
+			return false
+		}
+	}
+	return true
+}
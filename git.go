@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitMode reports whether the file list should come from git instead of
+// walking the given paths.
+func gitMode() bool {
+	return *gitChanged || *gitStaged || *gitSince != ""
+}
+
+// runGit runs git with args inside dir and returns its stdout.
+func runGit(dir string, args ...string) ([]byte, error) {
+	return runGitInput(dir, nil, args...)
+}
+
+// runGitInput is like runGit but feeds stdin to the command.
+func runGitInput(dir string, stdin []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// gitChangedFiles lists the .go files below root that git reports as changed.
+// root may be a directory or a single file.
+//
+//	staged:      files changed in the index (relative to since, or HEAD)
+//	since != "": files that differ between since and the worktree
+//	otherwise:   files that differ from HEAD, plus untracked files
+func gitChangedFiles(root string, since string, staged bool) ([]string, error) {
+	dir, pathspec := root, "*.go"
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		dir, pathspec = filepath.Dir(root), filepath.Base(root)
+	}
+
+	args := []string{"diff", "--name-only", "--relative", "--diff-filter=ACMR"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if since != "" {
+		args = append(args, since)
+	} else if !staged {
+		args = append(args, "HEAD")
+	}
+	args = append(args, "--", pathspec)
+
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	names := splitLines(out)
+
+	if !staged && since == "" {
+		untracked, err := runGit(dir, "ls-files", "--others", "--exclude-standard", "--", pathspec)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, splitLines(untracked)...)
+	}
+
+	seen := make(map[string]struct{}, len(names))
+	var files []string
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".go") || strings.HasPrefix(filepath.Base(name), ".") {
+			continue
+		}
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		if _, ok := seen[full]; ok {
+			continue
+		}
+		seen[full] = struct{}{}
+		// Staged files may no longer exist in the worktree.
+		if _, err := os.Stat(full); err != nil {
+			continue
+		}
+		files = append(files, full)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// formatIndex sorts the imports of every staged .go blob below root and
+// stores the result as a new blob in the index, leaving unstaged worktree
+// changes alone. root may be a directory or a single file. When
+// updateWorktree is set, the worktree copy is rewritten too, but only if it
+// still matches the staged content. With checkOnly nothing is written. It
+// returns the paths whose staged content is (or was) not sorted; files that
+// fail to process are recorded in the run summary and skipped.
+func formatIndex(root string, checkOnly, updateWorktree bool) ([]string, error) {
+	dir, pathspec := root, "*.go"
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		dir, pathspec = filepath.Dir(root), filepath.Base(root)
+	}
+
+	out, err := runGit(dir, "diff", "--cached", "--name-only", "--relative", "--diff-filter=ACMR", "--", pathspec)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, name := range splitLines(out) {
+		if !strings.HasSuffix(name, ".go") || strings.HasPrefix(filepath.Base(name), ".") {
+			continue
+		}
+		entry, err := runGit(dir, "ls-files", "--stage", "--full-name", "--", name)
+		if err != nil {
+			return changed, err
+		}
+		// <mode> SP <object> SP <stage> TAB <file>, the file relative to the
+		// top-level directory as update-index --cacheinfo expects it
+		info, fullName, _ := strings.Cut(strings.TrimSpace(string(entry)), "\t")
+		fields := strings.Fields(info)
+		if len(fields) < 2 || fullName == "" {
+			return changed, fmt.Errorf("unexpected index entry for %s: %q", name, entry)
+		}
+		mode, object := fields[0], fields[1]
+
+		src, err := runGit(dir, "cat-file", "blob", object)
+		if err != nil {
+			return changed, err
+		}
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		res, stats, err := processWithStats(src, full)
+		if err != nil {
+			summary.record(full, outcomeFailed, fileError(full, err))
+			continue
+		}
+		for _, warning := range stats.warnings {
+			logger.Warn(warning, "path", full)
+		}
+		if err := reportViolations(full, stats.violations); err != nil {
+			summary.record(full, outcomeFailed, fileError(full, err))
+			// an unsorted blob is still listed in check mode
+			if !bytes.Equal(src, res) {
+				changed = append(changed, full)
+			}
+			continue
+		}
+		if bytes.Equal(src, res) {
+			summary.record(full, outcomeUnchanged, nil)
+			continue
+		}
+		summary.record(full, outcomeChanged, nil)
+		changed = append(changed, full)
+		if checkOnly {
+			continue
+		}
+
+		hash, err := runGitInput(dir, res, "hash-object", "-w", "--stdin", "--path="+name)
+		if err != nil {
+			return changed, err
+		}
+		cacheInfo := mode + "," + strings.TrimSpace(string(hash)) + "," + fullName
+		if _, err := runGit(dir, "update-index", "--cacheinfo", cacheInfo); err != nil {
+			return changed, err
+		}
+
+		if updateWorktree {
+			if disk, err := os.ReadFile(full); err == nil && bytes.Equal(disk, src) {
+				mode := os.FileMode(0644)
+				if info, statErr := os.Stat(full); statErr == nil {
+					mode = info.Mode().Perm()
+				}
+				if err := os.WriteFile(full, res, mode); err != nil {
+					return changed, err
+				}
+			}
+		}
+	}
+	return changed, nil
+}
+
+// gitChangedPaths expands every path into the changed .go files below it.
+func gitChangedPaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var files []string
+	for _, path := range paths {
+		changed, err := gitChangedFiles(stripGoEllipsis(path), *gitSince, *gitStaged)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, changed...)
+	}
+	return files, nil
+}
+
+// splitLines splits command output into its non-empty lines.
+func splitLines(out []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
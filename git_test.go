@@ -0,0 +1,275 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newGitRepo creates a throwaway repository with one committed file.
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	// Resolve symlinks so paths compare equal to what git reports.
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "init", "-q")
+	writeFile(t, filepath.Join(dir, "committed.go"), "package a\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "init")
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	if _, err := runGit(dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	dir := newGitRepo(t)
+	writeFile(t, filepath.Join(dir, "committed.go"), "package a\n\nvar x int\n")
+	writeFile(t, filepath.Join(dir, "sub", "new.go"), "package sub\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not go\n")
+
+	got, err := gitChangedFiles(dir, "", false)
+	if err != nil {
+		t.Fatalf("gitChangedFiles: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "committed.go"),
+		filepath.Join(dir, "sub", "new.go"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGitChangedFiles_Staged(t *testing.T) {
+	dir := newGitRepo(t)
+	writeFile(t, filepath.Join(dir, "staged.go"), "package a\n")
+	writeFile(t, filepath.Join(dir, "unstaged.go"), "package a\n")
+	gitRun(t, dir, "add", "staged.go")
+
+	got, err := gitChangedFiles(dir, "", true)
+	if err != nil {
+		t.Fatalf("gitChangedFiles: %v", err)
+	}
+	want := []string{filepath.Join(dir, "staged.go")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGitChangedFiles_Since(t *testing.T) {
+	dir := newGitRepo(t)
+	writeFile(t, filepath.Join(dir, "second.go"), "package a\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "second")
+
+	got, err := gitChangedFiles(dir, "HEAD~1", false)
+	if err != nil {
+		t.Fatalf("gitChangedFiles: %v", err)
+	}
+	want := []string{filepath.Join(dir, "second.go")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := gitChangedFiles(dir, "no-such-rev", false); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+}
+
+func TestGitChangedPaths_ProcessPaths(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	resetBoolFlag(t, write)
+	resetBoolFlag(t, gitChanged)
+	*localPrefix = "github.com/myorg/myrepo"
+	*write = true
+	*gitChanged = true
+
+	dir := newGitRepo(t)
+	unsorted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	untouched := filepath.Join(dir, "untouched.go")
+	writeFile(t, untouched, unsorted)
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "unsorted")
+	changed := filepath.Join(dir, "changed.go")
+	writeFile(t, changed, unsorted)
+
+	files, err := gitChangedPaths([]string{dir + "/..."})
+	if err != nil {
+		t.Fatalf("gitChangedPaths: %v", err)
+	}
+	if err := processPaths(files, os.Stdout); err != nil {
+		t.Fatalf("processPaths: %v", err)
+	}
+
+	if disk, _ := os.ReadFile(untouched); string(disk) != unsorted {
+		t.Errorf("unchanged file should not be processed, got:\n%s", disk)
+	}
+	if disk, _ := os.ReadFile(changed); string(disk) == unsorted {
+		t.Errorf("changed file should be sorted, got:\n%s", disk)
+	}
+}
+
+func TestFormatIndex_KeepsUnstagedChanges(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	dir := newGitRepo(t)
+	staged := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	worktree := staged + "\nvar wip = 1\n"
+	file := filepath.Join(dir, "partial.go")
+	writeFile(t, file, staged)
+	gitRun(t, dir, "add", "partial.go")
+	writeFile(t, file, worktree)
+
+	changed, err := formatIndex(dir, false, true)
+	if err != nil {
+		t.Fatalf("formatIndex: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{file}) {
+		t.Errorf("changed = %v, want [%s]", changed, file)
+	}
+
+	index, err := runGit(dir, "show", ":partial.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"
+	if string(index) != want {
+		t.Errorf("index blob:\n%s\nwant:\n%s", index, want)
+	}
+	// The worktree copy differs from the index, so it must be left alone.
+	if disk, _ := os.ReadFile(file); string(disk) != worktree {
+		t.Errorf("worktree should keep unstaged changes, got:\n%s", disk)
+	}
+}
+
+func TestFormatIndex_UpdatesMatchingWorktree(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	dir := newGitRepo(t)
+	file := filepath.Join(dir, "clean.go")
+	writeFile(t, file, "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n")
+	gitRun(t, dir, "add", "clean.go")
+
+	if _, err := formatIndex(dir, false, true); err != nil {
+		t.Fatalf("formatIndex: %v", err)
+	}
+	index, err := runGit(dir, "show", ":clean.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disk, _ := os.ReadFile(file); string(disk) != string(index) {
+		t.Errorf("worktree should match index, got:\n%s\nindex:\n%s", disk, index)
+	}
+}
+
+func TestFormatIndex_FileArgument(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	dir := newGitRepo(t)
+	unsorted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	file := filepath.Join(dir, "sub", "one.go")
+	other := filepath.Join(dir, "sub", "two.go")
+	writeFile(t, file, unsorted)
+	writeFile(t, other, unsorted)
+	gitRun(t, dir, "add", ".")
+
+	changed, err := formatIndex(file, false, false)
+	if err != nil {
+		t.Fatalf("formatIndex: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{file}) {
+		t.Errorf("changed = %v, want [%s]", changed, file)
+	}
+	if index, _ := runGit(dir, "show", ":sub/two.go"); string(index) != unsorted {
+		t.Errorf("other files must not be touched, got:\n%s", index)
+	}
+}
+
+func TestFormatIndex_CheckOnly(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	dir := newGitRepo(t)
+	sorted := "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"
+	unsorted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	file := filepath.Join(dir, "partial.go")
+
+	// Staged content is unsorted, the worktree copy sorted: check the stage.
+	writeFile(t, file, unsorted)
+	gitRun(t, dir, "add", "partial.go")
+	writeFile(t, file, sorted)
+	changed, err := formatIndex(dir, true, false)
+	if err != nil {
+		t.Fatalf("formatIndex: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{file}) {
+		t.Errorf("changed = %v, want [%s]", changed, file)
+	}
+	if index, _ := runGit(dir, "show", ":partial.go"); string(index) != unsorted {
+		t.Errorf("check mode must not update the index, got:\n%s", index)
+	}
+
+	// And the other way round: unsorted unstaged edits do not fail the check.
+	gitRun(t, dir, "add", "partial.go")
+	writeFile(t, file, unsorted)
+	if changed, err := formatIndex(dir, true, false); err != nil || len(changed) != 0 {
+		t.Errorf("formatIndex = %v, %v, want no changes", changed, err)
+	}
+}
+
+func TestFormatIndex_CheckDeniedImports(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+	resetBoolFlag(t, check)
+	*check = true
+	prev := summary
+	t.Cleanup(func() { summary = prev })
+	summary = newRunSummary()
+
+	dir := newGitRepo(t)
+	writeFile(t, filepath.Join(dir, configFileName), `{"rules": [{"files": ["api/**"], "deny": [{"path": "io/ioutil"}]}]}`)
+	c, err := loadConfig(filepath.Join(dir, configFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfig(t, c)
+	writeFile(t, filepath.Join(dir, "api", "a.go"), "package api\n\nimport \"io/ioutil\"\n\nvar _ = ioutil.ReadAll\n")
+	gitRun(t, dir, "add", ".")
+
+	if _, err := formatIndex(dir, true, false); err != nil {
+		t.Fatalf("formatIndex: %v", err)
+	}
+	if summary.failed != 1 {
+		t.Errorf("expected the denied import to fail the file, got %+v", *summary)
+	}
+	if err := summary.report(); err == nil || !strings.Contains(err.Error(), "1 of 1 files failed") {
+		t.Errorf("report() = %v, want a failure", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"go/build"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// findGoroot returns the GOROOT to read std packages from: the GOROOT
+// environment variable, otherwise `go env GOROOT`.
+func findGoroot() (string, error) {
+	if goroot := os.Getenv("GOROOT"); goroot != "" {
+		return goroot, nil
+	}
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return "", err
+	}
+	goroot := strings.TrimSpace(string(out))
+	if goroot == "" {
+		return "", errors.New("go env GOROOT is empty")
+	}
+	return goroot, nil
+}
+
+// walkGorootPackages derives the std package set by walking $GOROOT/src,
+// the same set `go list std` reports (without vendored packages) but
+// without running the go command. Build constraints are ignored so the set
+// does not depend on GOOS/GOARCH and matches the embedded tables.
+func walkGorootPackages(goroot string) (map[string]struct{}, error) {
+	src := filepath.Join(goroot, "src")
+	ctxt := build.Default
+	ctxt.GOROOT = goroot
+	ctxt.UseAllFiles = true
+
+	packages := make(map[string]struct{})
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != src {
+			name := d.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if path == filepath.Join(src, "cmd") {
+				return filepath.SkipDir
+			}
+		}
+
+		pkg, err := ctxt.ImportDir(path, build.IgnoreVendor)
+		if err != nil {
+			var noGo *build.NoGoError
+			if errors.As(err, &noGo) {
+				return nil
+			}
+			// other errors (e.g. conflicting package clauses from files
+			// that are normally excluded) still describe a package
+			if pkg == nil || pkg.Name == "" {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		// builtin only documents predeclared identifiers, the go command
+		// leaves it out of "std" as well
+		if rel == "builtin" {
+			return nil
+		}
+		packages[filepath.ToSlash(rel)] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
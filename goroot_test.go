@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestFindGoroot_Env(t *testing.T) {
+	t.Setenv("GOROOT", "/opt/custom/go")
+	got, err := findGoroot()
+	if err != nil {
+		t.Fatalf("findGoroot: %v", err)
+	}
+	if got != "/opt/custom/go" {
+		t.Errorf("findGoroot() = %q, want /opt/custom/go", got)
+	}
+}
+
+func TestWalkGorootPackages_MatchesPackagesLoad(t *testing.T) {
+	goroot, err := findGoroot()
+	if err != nil {
+		t.Skipf("no GOROOT: %v", err)
+	}
+	walked, err := walkGorootPackages(goroot)
+	if err != nil {
+		t.Fatalf("walkGorootPackages: %v", err)
+	}
+	pkgs, err := packages.Load(nil, "std")
+	if err != nil {
+		t.Skipf("go command not available: %v", err)
+	}
+
+	// the go command drops packages excluded on this platform, the walk
+	// keeps them, so only check that nothing is missing
+	for _, p := range pkgs {
+		if strings.HasPrefix(p.PkgPath, "vendor/") {
+			continue
+		}
+		if _, ok := walked[p.PkgPath]; !ok {
+			t.Errorf("walk misses %s", p.PkgPath)
+		}
+	}
+}
+
+func TestWalkGorootPackages_MatchesEmbedded(t *testing.T) {
+	goroot, err := findGoroot()
+	if err != nil {
+		t.Skipf("no GOROOT: %v", err)
+	}
+	embedded, ok := embeddedStdPackages(runtime.Version())
+	if !ok {
+		t.Skipf("no embedded table for %s", runtime.Version())
+	}
+	walked, err := walkGorootPackages(goroot)
+	if err != nil {
+		t.Fatalf("walkGorootPackages: %v", err)
+	}
+
+	// the embedded table only lists packages with a public API, so internal
+	// and experiment-only packages may be walked in addition
+	for p := range embedded {
+		if _, ok := walked[p]; !ok {
+			t.Errorf("walk misses embedded package %s", p)
+		}
+	}
+	for _, p := range []string{"syscall/js", "internal/syscall/windows", "crypto/x509/internal/macos"} {
+		if _, ok := walked[p]; !ok {
+			t.Errorf("walk misses platform-specific package %s", p)
+		}
+	}
+}
+
+func TestWalkGorootPackages_Excludes(t *testing.T) {
+	goroot := t.TempDir()
+	for rel, content := range map[string]string{
+		"src/fmt/print.go":             "package fmt\n",
+		"src/fmt/testdata/x.go":        "package x\n",
+		"src/cmd/go/main.go":           "package main\n",
+		"src/vendor/golang.org/x/a.go": "package x\n",
+		"src/_asm/gen.go":              "package asm\n",
+		"src/js/only.go":               "//go:build js\n\npackage js\n",
+		"src/onlytest/x_test.go":       "package onlytest\n",
+		"src/empty/README":             "no go files\n",
+	} {
+		path := filepath.Join(goroot, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := walkGorootPackages(goroot)
+	if err != nil {
+		t.Fatalf("walkGorootPackages: %v", err)
+	}
+	want := []string{"fmt", "js", "onlytest"}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, p := range want {
+		if _, ok := got[p]; !ok {
+			t.Errorf("expected %s in %v", p, got)
+		}
+	}
+}
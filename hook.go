@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	hookBegin   = "# >>> sortimport >>>"
+	hookEnd     = "# <<< sortimport <<<"
+	hookCommand = "sortimport -check -index || exit 1"
+	hookShebang = "#!/bin/sh"
+)
+
+// hookBlock is the marked section managed by install-hook.
+var hookBlock = hookBegin + "\n" + hookCommand + "\n" + hookEnd + "\n"
+
+// installHookMain implements the install-hook subcommand.
+func installHookMain(args []string) error {
+	fs := flag.NewFlagSet("install-hook", flag.ExitOnError)
+	uninstall := fs.Bool("uninstall", false, "remove the sortimport section from the pre-commit hook")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr, "usage: sortimport install-hook [-uninstall] [dir]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	if *uninstall {
+		hook, err := uninstallHook(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("removed sortimport from %s\n", hook)
+		return nil
+	}
+	hook, err := installHook(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("installed sortimport in %s\n", hook)
+	return nil
+}
+
+// hooksDir returns the hooks directory of the repository containing dir,
+// honouring core.hooksPath.
+func hooksDir(dir string) (string, error) {
+	if out, err := runGit(dir, "config", "core.hooksPath"); err == nil {
+		if p := strings.TrimSpace(string(out)); p != "" {
+			if filepath.IsAbs(p) {
+				return p, nil
+			}
+			// Relative hooksPath is resolved against the worktree root.
+			top, err := runGit(dir, "rev-parse", "--show-toplevel")
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(strings.TrimSpace(string(top)), p), nil
+		}
+	}
+
+	out, err := runGit(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	common := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(dir, common)
+	}
+	return filepath.Join(common, "hooks"), nil
+}
+
+// installHook adds the sortimport section to the pre-commit hook of the
+// repository containing dir, creating the hook if needed. Installing twice
+// is a no-op; a section written by an older version is replaced. It
+// returns the hook path.
+func installHook(dir string) (string, error) {
+	hooks, err := hooksDir(dir)
+	if err != nil {
+		return "", err
+	}
+	hook := filepath.Join(hooks, "pre-commit")
+
+	content, err := os.ReadFile(hook)
+	switch {
+	case os.IsNotExist(err):
+		content = []byte(hookShebang + "\n")
+	case err != nil:
+		return "", err
+	case strings.Contains(string(content), hookBlock):
+		return hook, nil
+	}
+
+	text, err := removeHookSection(hook, string(content))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	text += hookBlock
+
+	if err := os.MkdirAll(hooks, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(hook, []byte(text), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file; make sure it runs.
+	return hook, os.Chmod(hook, 0755)
+}
+
+// uninstallHook removes the sortimport section from the pre-commit hook,
+// deleting the hook when nothing else is left in it.
+func uninstallHook(dir string) (string, error) {
+	hooks, err := hooksDir(dir)
+	if err != nil {
+		return "", err
+	}
+	hook := filepath.Join(hooks, "pre-commit")
+
+	content, err := os.ReadFile(hook)
+	if os.IsNotExist(err) {
+		return hook, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if !strings.Contains(string(content), hookBegin) {
+		return hook, nil
+	}
+	text, err := removeHookSection(hook, string(content))
+	if err != nil {
+		return "", err
+	}
+
+	if rest := strings.TrimSpace(text); rest == "" || rest == hookShebang {
+		return hook, os.Remove(hook)
+	}
+	info, err := os.Stat(hook)
+	if err != nil {
+		return "", err
+	}
+	return hook, os.WriteFile(hook, []byte(text), info.Mode().Perm())
+}
+
+// removeHookSection returns the hook text without its sortimport section.
+func removeHookSection(hook, text string) (string, error) {
+	start := strings.Index(text, hookBegin)
+	if start < 0 {
+		return text, nil
+	}
+	end := strings.Index(text[start:], hookEnd)
+	if end < 0 {
+		return "", fmt.Errorf("%s: unterminated sortimport section", hook)
+	}
+	end += start + len(hookEnd)
+	if end < len(text) && text[end] == '\n' {
+		end++
+	}
+	return text[:start] + text[end:], nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallHook_Idempotent(t *testing.T) {
+	dir := newGitRepo(t)
+
+	hook, err := installHook(dir)
+	if err != nil {
+		t.Fatalf("installHook: %v", err)
+	}
+	if want := filepath.Join(dir, ".git", "hooks", "pre-commit"); hook != want {
+		t.Errorf("hook = %s, want %s", hook, want)
+	}
+	if _, err := installHook(dir); err != nil {
+		t.Fatalf("second installHook: %v", err)
+	}
+
+	content, err := os.ReadFile(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), hookShebang+"\n") {
+		t.Errorf("expected shebang, got:\n%s", content)
+	}
+	if got := strings.Count(string(content), hookBegin); got != 1 {
+		t.Errorf("expected one sortimport section, got %d:\n%s", got, content)
+	}
+	if info, _ := os.Stat(hook); info.Mode().Perm()&0100 == 0 {
+		t.Errorf("hook should be executable, mode %o", info.Mode().Perm())
+	}
+
+	if _, err := uninstallHook(dir); err != nil {
+		t.Fatalf("uninstallHook: %v", err)
+	}
+	if _, err := os.Stat(hook); !os.IsNotExist(err) {
+		t.Errorf("expected hook to be removed, stat err = %v", err)
+	}
+}
+
+func TestInstallHook_AppendsToExisting(t *testing.T) {
+	dir := newGitRepo(t)
+	existing := "#!/bin/sh\nmake lint"
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	writeFile(t, hook, existing)
+
+	if _, err := installHook(dir); err != nil {
+		t.Fatalf("installHook: %v", err)
+	}
+	content, _ := os.ReadFile(hook)
+	if want := existing + "\n" + hookBlock; string(content) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", content, want)
+	}
+
+	if _, err := uninstallHook(dir); err != nil {
+		t.Fatalf("uninstallHook: %v", err)
+	}
+	content, _ = os.ReadFile(hook)
+	if string(content) != existing+"\n" {
+		t.Errorf("uninstall should restore the original hook, got:\n%s", content)
+	}
+}
+
+func TestInstallHook_HooksPath(t *testing.T) {
+	dir := newGitRepo(t)
+	gitRun(t, dir, "config", "core.hooksPath", "githooks")
+
+	hook, err := installHook(dir)
+	if err != nil {
+		t.Fatalf("installHook: %v", err)
+	}
+	if want := filepath.Join(dir, "githooks", "pre-commit"); hook != want {
+		t.Errorf("hook = %s, want %s", hook, want)
+	}
+	if _, err := os.Stat(hook); err != nil {
+		t.Errorf("expected hook file: %v", err)
+	}
+}
+
+func TestInstallHook_ReplacesOldSection(t *testing.T) {
+	dir := newGitRepo(t)
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	old := hookShebang + "\necho before\n" + hookBegin + "\nsortimport -check -staged || exit 1\n" + hookEnd + "\n"
+	writeFile(t, hook, old)
+
+	if _, err := installHook(dir); err != nil {
+		t.Fatalf("installHook: %v", err)
+	}
+	content, err := os.ReadFile(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hookShebang + "\necho before\n" + hookBlock; string(content) != want {
+		t.Errorf("hook:\n%s\nwant:\n%s", content, want)
+	}
+	if !strings.Contains(hookCommand, "-index") {
+		t.Errorf("the hook must check the staged blobs, got %q", hookCommand)
+	}
+}
@@ -25,11 +25,16 @@ const (
 	GroupThird
 	GroupSecond
 	GroupLocal
+	GroupBlank // only with a blank section, see sectionOrder
+	GroupDot   // only with a dot section
 	GroupCount
 )
 
 type impManager struct {
-	groups []*impGroup
+	groups     []*impGroup
+	warnings   []string          // problems found with the imports, see warnf
+	violations []importViolation // imports denied by the config rules
+	rewritten  []string          // import paths changed, see rewritePath
 }
 
 type impGroup struct {
@@ -50,6 +55,11 @@ func newImpManager() *impManager {
 	return &impManager{groups: groups}
 }
 
+// warnf records a problem with the imports, reported once the file is processed
+func (m *impManager) warnf(format string, args ...any) {
+	m.warnings = append(m.warnings, fmt.Sprintf(format, args...))
+}
+
 func (m *impManager) Standard() *impGroup {
 	return m.groups[GroupStandard]
 }
@@ -83,11 +93,16 @@ func (g *impGroup) sortImports() {
 	})
 }
 
-// convertImportsToGo generates output for correct categorised import statements
+// convertImportsToGo generates output for correct categorised import statements,
+// nothing when there are no imports
 func (m *impManager) convertImportsToGo() []byte {
+	if m.countImports() == 0 {
+		return nil
+	}
 	output := "import ("
 
-	for _, group := range m.groups {
+	for _, idx := range sectionOrder {
+		group := m.groups[idx]
 		if group.countImports() == 0 {
 			continue
 		}
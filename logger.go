@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+var (
+	// logLevel is the lowest level printed, set by -v and -q
+	logLevel = new(slog.LevelVar)
+	// logger writes all diagnostics to stderr; stdout is reserved for results
+	logger = slog.New(newLogHandler(os.Stderr, "text", false))
+)
+
+// newLogHandler creates a text or json handler; timestamps are only
+// included when withTime is set, keeping default output terse.
+func newLogHandler(w io.Writer, format string, withTime bool) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if !withTime && len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// setupLogging configures format and verbosity: verbose logs everything
+// with timestamps, quiet only errors.
+func setupLogging(w io.Writer, format string, verbose, quiet bool) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown log format %q, want text or json", format)
+	}
+	switch {
+	case verbose:
+		logLevel.Set(slog.LevelDebug)
+	case quiet:
+		logLevel.Set(slog.LevelError)
+	default:
+		logLevel.Set(slog.LevelInfo)
+	}
+	logger = slog.New(newLogHandler(w, format, verbose))
+	return nil
+}
+
+func logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+// debugf logs details only shown with -v
+func debugf(format string, args ...any) { logf(slog.LevelDebug, format, args...) }
+
+// infof logs progress messages
+func infof(format string, args ...any) { logf(slog.LevelInfo, format, args...) }
+
+// warnf logs recoverable problems
+func warnf(format string, args ...any) { logf(slog.LevelWarn, format, args...) }
+
+// errorf logs failures
+func errorf(format string, args ...any) { logf(slog.LevelError, format, args...) }
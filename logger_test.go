@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func captureLogs(t *testing.T, format string, verbose, quiet bool) *bytes.Buffer {
+	t.Helper()
+	prevLogger, prevLevel := logger, logLevel.Level()
+	t.Cleanup(func() {
+		logger = prevLogger
+		logLevel.Set(prevLevel)
+	})
+	var buf bytes.Buffer
+	if err := setupLogging(&buf, format, verbose, quiet); err != nil {
+		t.Fatalf("setupLogging: %v", err)
+	}
+	return &buf
+}
+
+func TestLogLevels(t *testing.T) {
+	tests := []struct {
+		name           string
+		verbose, quiet bool
+		want           []string
+	}{
+		{"default", false, false, []string{"INFO", "WARN", "ERROR"}},
+		{"verbose", true, false, []string{"DEBUG", "INFO", "WARN", "ERROR"}},
+		{"quiet", false, true, []string{"ERROR"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t, "text", tt.verbose, tt.quiet)
+			debugf("debug %d", 1)
+			infof("info %d", 2)
+			warnf("warn %d", 3)
+			errorf("error %d", 4)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(tt.want), buf.String())
+			}
+			for i, level := range tt.want {
+				if !strings.Contains(lines[i], "level="+level) {
+					t.Errorf("line %d = %q, want level %s", i, lines[i], level)
+				}
+			}
+			if strings.Contains(buf.String(), "time=") != tt.verbose {
+				t.Errorf("timestamps should only be logged in verbose mode:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestSetupLogging_UnknownFormat(t *testing.T) {
+	if err := setupLogging(&bytes.Buffer{}, "xml", false, false); err == nil {
+		t.Error("expected error for unknown log format")
+	}
+}
+
+func TestProcessFile_LogsFileAttributes(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+	buf := captureLogs(t, "json", true, false)
+
+	fp := filepath.Join(t.TempDir(), "in.go")
+	src := "package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n\n\t\"github.com/x/y\"\n)\n"
+	if err := os.WriteFile(fp, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processFile(fp, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("processFile: %v", err)
+	}
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not json: %q", line)
+		}
+		if record["msg"] != "processed file" {
+			continue
+		}
+		found = true
+		if record["path"] != fp || record["module"] != "github.com/myorg/myrepo" {
+			t.Errorf("unexpected path/module in %v", record)
+		}
+		if record["groups"] != float64(2) || record["imports"] != float64(3) {
+			t.Errorf("unexpected groups/imports in %v", record)
+		}
+		if _, ok := record["duration"]; !ok {
+			t.Errorf("expected duration in %v", record)
+		}
+	}
+	if !found {
+		t.Errorf("no processed file record in:\n%s", buf.String())
+	}
+}
+
+func TestProcessFile_LogsWarningsWithPath(t *testing.T) {
+	buf := captureLogs(t, "json", false, false)
+
+	fp := filepath.Join(t.TempDir(), "in.go")
+	src := "package main\n\nimport APA \"errors\"\nimport APZ \"errors\"\n\nvar _, _ = APA.New, APZ.New\n"
+	if err := os.WriteFile(fp, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processFile(fp, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("processFile: %v", err)
+	}
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not json: %q", line)
+		}
+		if record["level"] != "WARN" {
+			continue
+		}
+		found = true
+		if record["msg"] != `"errors" is imported 2 times, as APA "errors", APZ "errors"` || record["path"] != fp {
+			t.Errorf("unexpected warning record %v", record)
+		}
+	}
+	if !found {
+		t.Errorf("no warning record in:\n%s", buf.String())
+	}
+}
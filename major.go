@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// majorPath returns modulePath at major version v ("v0" to "vN"), e.g.
+// github.com/foo/bar/v2 at v3 is github.com/foo/bar/v3.
+func majorPath(modulePath, version string) (string, error) {
+	if !isMajorSuffix(version) && version != "v0" && version != "v1" {
+		return "", fmt.Errorf("invalid major version %q, want e.g. v2", version)
+	}
+	if strings.HasPrefix(modulePath, "gopkg.in/") {
+		return "", fmt.Errorf("%s: gopkg.in paths are not supported", modulePath)
+	}
+	if err := module.CheckImportPath(modulePath); err != nil {
+		return "", err
+	}
+	base, _, _ := module.SplitPathVersion(modulePath)
+	if version == "v0" || version == "v1" {
+		return base, nil
+	}
+	return base + "/" + version, nil
+}
+
+// migrateMajorMain implements the migrate-major subcommand: imports of any
+// major version of a module, including its subpackages, are rewritten to
+// the given major version and the imports re-sorted. The remaining
+// arguments are the usual flags and paths.
+func migrateMajorMain(args []string) error {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		_, _ = fmt.Fprintf(os.Stderr, "usage: sortimport migrate-major <module> <vN> [flags] [path ...]\n")
+		return errors.New("missing module or major version")
+	}
+	target, err := majorPath(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	base, _, _ := module.SplitPathVersion(target)
+	rewrites = append(rewrites, pathRewrite{old: base, new: target, anyMajor: true})
+	return sortMain(args[2:])
+}
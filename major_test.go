@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestMajorPath(t *testing.T) {
+	tests := []struct {
+		module, version, want string
+		wantErr               bool
+	}{
+		{"github.com/foo/bar", "v2", "github.com/foo/bar/v2", false},
+		{"github.com/foo/bar/v3", "v4", "github.com/foo/bar/v4", false},
+		{"github.com/foo/bar/v2", "v1", "github.com/foo/bar", false},
+		{"github.com/foo/bar", "2", "", true},
+		{"github.com/foo/bar", "v2.1.0", "", true},
+		{"gopkg.in/yaml.v2", "v3", "", true},
+	}
+	for _, tt := range tests {
+		got, err := majorPath(tt.module, tt.version)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("majorPath(%q, %q) = %q, %v, want %q, error %v", tt.module, tt.version, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMigrateMajor_Rewrite(t *testing.T) {
+	setRewrites(t)
+	rewrites = append(rewrites, pathRewrite{old: "github.com/foo/bar", new: "github.com/foo/bar/v4", anyMajor: true})
+
+	src := `package main
+
+import (
+	"fmt"
+
+	"github.com/foo/bar"
+	bar3 "github.com/foo/bar/v3"
+	"github.com/foo/bar/v2/sub"
+	"github.com/foo/bar/v4/other"
+	"github.com/foo/barbaz"
+)
+`
+	want := `package main
+
+import (
+	"fmt"
+
+	"github.com/foo/bar/v4"
+	bar3 "github.com/foo/bar/v4"
+	"github.com/foo/bar/v4/other"
+	"github.com/foo/bar/v4/sub"
+	"github.com/foo/barbaz"
+)
+`
+	got, stats, err := processWithStats([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if len(stats.rewritten) != 3 {
+		t.Errorf("rewritten = %q, want 3 changes", stats.rewritten)
+	}
+}
+
+func TestMigrateMajorMain_Usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"github.com/foo/bar"}, {"github.com/foo/bar", "-w"}} {
+		if err := migrateMajorMain(args); err == nil {
+			t.Errorf("migrateMajorMain(%q) should fail", args)
+		}
+	}
+}
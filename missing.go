@@ -0,0 +1,192 @@
+package main
+
+import (
+	_ "embed"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stdSymbolsTable lists the exported package-level names of every std
+// package, see gen_stdlib.go
+//
+//go:embed std_symbols.txt
+var stdSymbolsTable string
+
+// stdSymbols indexes stdSymbolsTable by package name, then import path,
+// built on first use
+var stdSymbols map[string]map[string]map[string]struct{}
+
+func stdSymbolIndex() map[string]map[string]map[string]struct{} {
+	if stdSymbols != nil {
+		return stdSymbols
+	}
+	stdSymbols = make(map[string]map[string]map[string]struct{})
+	for _, line := range strings.Split(stdSymbolsTable, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		path, name := fields[0], assumedPackageName(fields[0])
+		names := make(map[string]struct{}, len(fields)-1)
+		for _, sym := range fields[1:] {
+			names[sym] = struct{}{}
+		}
+		if stdSymbols[name] == nil {
+			stdSymbols[name] = make(map[string]map[string]struct{})
+		}
+		stdSymbols[name][path] = names
+	}
+	return stdSymbols
+}
+
+// stdImportFor returns the std package named name that exports all of
+// symbols. Among several candidates the one listed in -prefer wins; it
+// reports false when there is none or the choice is ambiguous.
+func stdImportFor(name string, symbols map[string]bool, std map[string]struct{}) (string, bool) {
+	var candidates []string
+	for path, names := range stdSymbolIndex()[name] {
+		if !isStandardPackageIn(std, path) {
+			continue
+		}
+		exportsAll := true
+		for sym := range symbols {
+			if _, ok := names[sym]; !ok {
+				exportsAll = false
+				break
+			}
+		}
+		if exportsAll {
+			candidates = append(candidates, path)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	for _, preferred := range strings.Split(*preferImports, ",") {
+		for _, path := range candidates {
+			if path == strings.TrimSpace(preferred) {
+				return path, true
+			}
+		}
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		infof("%s is ambiguous (%s), choose one with -prefer", name, strings.Join(candidates, ", "))
+	}
+	return "", false
+}
+
+// addMissing adds the std imports for package names referenced in the file
+// (see referencedPackages) that no import provides, and returns their
+// paths. Names declared in other files of the package are left alone.
+func (m *impManager) addMissing(used map[string]map[string]bool, filePath string, std map[string]struct{}) []string {
+	provided := make(map[string]bool)
+	for _, group := range m.groups {
+		for _, imp := range group.models {
+			if name, ok := importName(imp, filePath); ok {
+				provided[name] = true
+			} else if imp.localReference == "" {
+				provided[assumedPackageName(strings.Trim(imp.path, "\""))] = true
+			}
+		}
+	}
+
+	var declared map[string]bool
+	var added []string
+	for name, symbols := range used {
+		if provided[name] {
+			continue
+		}
+		if declared == nil {
+			declared = packageLevelNames(filePath)
+		}
+		if declared[name] {
+			continue
+		}
+		path, ok := stdImportFor(name, symbols, std)
+		if !ok {
+			continue
+		}
+		m.Standard().append(&impModel{path: strconv.Quote(path)})
+		provided[name] = true
+		added = append(added, path)
+	}
+	sort.Strings(added)
+	return added
+}
+
+// packageDecls memoizes the package-level names declared by each go file,
+// per directory, for the run
+var packageDecls = make(map[string]map[string][]string)
+
+// packageLevelNames returns the names declared at package level in the
+// other go files of the directory of filePath.
+func packageLevelNames(filePath string) map[string]bool {
+	names := make(map[string]bool)
+	if filePath == "" {
+		return names
+	}
+	dir := filepath.Dir(filePath)
+	decls, ok := packageDecls[dir]
+	if !ok {
+		decls = readPackageDecls(dir)
+		packageDecls[dir] = decls
+	}
+	for path, declared := range decls {
+		if path == filepath.Clean(filePath) {
+			continue
+		}
+		for _, name := range declared {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// readPackageDecls parses the go files of dir and returns the names each
+// declares at package level, by file path.
+func readPackageDecls(dir string) map[string][]string {
+	decls := make(map[string][]string)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return decls
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		var names []string
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					names = append(names, decl.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						for _, ident := range spec.Names {
+							names = append(names, ident.Name)
+						}
+					case *ast.TypeSpec:
+						names = append(names, spec.Name.Name)
+					}
+				}
+			}
+		}
+		decls[path] = names
+	}
+	return decls
+}
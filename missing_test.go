@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStdImportFor(t *testing.T) {
+	resetStringFlag(t, preferImports)
+	std := standardPackagesFor("go1.22")
+	tests := []struct {
+		name    string
+		symbols []string
+		prefer  string
+		want    string
+		wantOK  bool
+	}{
+		{"strings", []string{"Builder"}, "", "strings", true},
+		{"http", []string{"Get"}, "", "net/http", true},
+		{"rand", []string{"Intn"}, "", "math/rand", true},
+		{"rand", []string{"IntN"}, "", "math/rand/v2", true},
+		{"rand", []string{"Read"}, "", "", false},
+		{"rand", []string{"Read"}, "crypto/rand", "crypto/rand", true},
+		{"strings", []string{"NoSuchThing"}, "", "", false},
+		{"iter", []string{"Seq"}, "", "", false}, // go1.23+
+	}
+	for _, tt := range tests {
+		*preferImports = tt.prefer
+		symbols := make(map[string]bool)
+		for _, s := range tt.symbols {
+			symbols[s] = true
+		}
+		got, ok := stdImportFor(tt.name, symbols, std)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("stdImportFor(%s, %v, prefer %q) = %q, %v, want %q, %v", tt.name, tt.symbols, tt.prefer, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestProcess_AddMissing(t *testing.T) {
+	resetBoolFlag(t, addMissing)
+	*addMissing = true
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "other.go"), "package main\n\nvar json = struct{ Marshal func() }{}\n")
+	file := filepath.Join(dir, "main.go")
+
+	tests := map[string]struct{ src, want string }{
+		"existing imports": {
+			src: `package main
+
+import (
+	"fmt"
+	"github.com/x/y"
+)
+
+func main() {
+	var b strings.Builder
+	fmt.Println(b.String(), y.Z, json.Marshal)
+	http.Get("x")
+}
+`,
+			want: `package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/x/y"
+)
+
+func main() {
+	var b strings.Builder
+	fmt.Println(b.String(), y.Z, json.Marshal)
+	http.Get("x")
+}
+`,
+		},
+		"no imports": {
+			src: `package main
+
+// main prints
+func main() { fmt.Println() }
+`,
+			want: `package main
+
+import (
+	"fmt"
+)
+
+// main prints
+func main() { fmt.Println() }
+`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := process([]byte(tt.src), file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageLevelNames_Memoized(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.go"), "package p\n\nfunc A() {}\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package p\n\ntype B struct{}\n\nvar c, d int\n")
+	t.Cleanup(func() { delete(packageDecls, dir) })
+
+	names := packageLevelNames(filepath.Join(dir, "a.go"))
+	if names["A"] || !names["B"] || !names["c"] || !names["d"] {
+		t.Errorf("names of a.go siblings = %v", names)
+	}
+	if _, ok := packageDecls[dir]; !ok {
+		t.Fatal("expected the directory to be memoized")
+	}
+
+	// siblings are parsed once per run
+	writeFile(t, filepath.Join(dir, "e.go"), "package p\n\nconst E = 1\n")
+	names = packageLevelNames(filepath.Join(dir, "b.go"))
+	if !names["A"] || names["B"] || names["E"] {
+		t.Errorf("names of b.go siblings = %v", names)
+	}
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/mod/modfile"
 )
@@ -13,13 +13,13 @@ import (
 func getModuleName() string {
 	root, err := os.Getwd()
 	if err != nil {
-		log.Println("error when getting root path: ", err)
+		debugf("error when getting root path: %v", err)
 		return ""
 	}
 
 	goModBytes, err := os.ReadFile(filepath.Join(root, "go.mod"))
 	if err != nil {
-		log.Println("error when reading mod file: ", err)
+		debugf("error when reading mod file: %v", err)
 		return ""
 	}
 
@@ -32,46 +32,135 @@ func getModuleName() string {
 // traversing up the directory tree until found or reaching the root.
 // Returns the module path from go.mod, or empty string if not found.
 func findModulePath(startPath string) string {
+	goModPath := findGoMod(startPath)
+	if goModPath == "" {
+		return ""
+	}
+	f := readGoMod(goModPath)
+	if f == nil || f.Module == nil {
+		return ""
+	}
+	return f.Module.Mod.Path
+}
+
+// goModDirs memoizes findGoMod per path for the run
+var goModDirs = make(map[string]string)
+
+// findGoMod returns the path of the go.mod governing startPath,
+// or empty string if not found.
+func findGoMod(startPath string) string {
 	// Get absolute path
 	absPath, err := filepath.Abs(startPath)
 	if err != nil {
-		log.Println("error when getting absolute path: ", err)
+		debugf("error when getting absolute path: %v", err)
 		return ""
 	}
+	if goModPath, ok := goModDirs[absPath]; ok {
+		return goModPath
+	}
 
 	// If it's a file, start from its directory
-	info, err := os.Stat(absPath)
-	if err == nil && !info.IsDir() {
-		absPath = filepath.Dir(absPath)
+	dir := absPath
+	if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+		dir = filepath.Dir(absPath)
+	}
+	goModPath, ok := goModDirs[dir]
+	if !ok {
+		goModPath = searchGoMod(dir)
+		goModDirs[dir] = goModPath
 	}
+	goModDirs[absPath] = goModPath
+	return goModPath
+}
 
-	// Traverse up the directory tree
-	currentPath := absPath
+// searchGoMod traverses up the directory tree from dir to find go.mod.
+func searchGoMod(dir string) string {
+	currentPath := dir
 	for {
 		goModPath := filepath.Join(currentPath, "go.mod")
 		if _, err := os.Stat(goModPath); err == nil {
-			// Found go.mod, parse it
-			goModBytes, err := os.ReadFile(goModPath)
-			if err != nil {
-				log.Println("error when reading mod file: ", err)
-				return ""
-			}
-			modName := modfile.ModulePath(goModBytes)
-			log.Printf("found module %s from %s\n", modName, goModPath)
-			return modName
+			return goModPath
 		}
 
 		// Move up one directory
 		parentPath := filepath.Dir(currentPath)
 		if parentPath == currentPath {
 			// Reached root, no go.mod found
-			log.Println("no go.mod found in directory tree")
+			debugf("no go.mod found in directory tree")
 			return ""
 		}
 		currentPath = parentPath
 	}
 }
 
+// goModFile is a memoized parse of a go.mod file
+type goModFile struct {
+	modTime time.Time
+	size    int64
+	file    *modfile.File // nil when unreadable
+}
+
+// goModFiles memoizes readGoMod per go.mod path. Entries are revalidated
+// by size and modification time, so watch mode sees edits.
+var goModFiles = make(map[string]*goModFile)
+
+// readGoMod parses a go.mod file once per run, returning nil when it
+// cannot be read or parsed.
+func readGoMod(goModPath string) *modfile.File {
+	info, err := os.Stat(goModPath)
+	if err != nil {
+		debugf("error when reading mod file: %v", err)
+		return nil
+	}
+	if m, ok := goModFiles[goModPath]; ok && m.modTime.Equal(info.ModTime()) && m.size == info.Size() {
+		return m.file
+	}
+
+	entry := &goModFile{modTime: info.ModTime(), size: info.Size()}
+	goModFiles[goModPath] = entry
+	goModBytes, err := os.ReadFile(goModPath)
+	if err != nil {
+		debugf("error when reading mod file: %v", err)
+		return nil
+	}
+	// ParseLax drops the toolchain directive, so try a strict parse first
+	f, err := modfile.Parse(goModPath, goModBytes, nil)
+	if err != nil {
+		f, err = modfile.ParseLax(goModPath, goModBytes, nil)
+	}
+	if err != nil {
+		debugf("error when parsing mod file: %v", err)
+		return nil
+	}
+	debugf("found module %s from %s", modfile.ModulePath(goModBytes), goModPath)
+	entry.file = f
+	return f
+}
+
+// moduleGoVersion returns the Go version the module containing filePath
+// is built with: its toolchain directive if present, otherwise its go
+// directive (e.g. "go1.22"). Returns empty string if unknown.
+func moduleGoVersion(filePath string) string {
+	if filePath == "" {
+		return ""
+	}
+	goModPath := findGoMod(filePath)
+	if goModPath == "" {
+		return ""
+	}
+	f := readGoMod(goModPath)
+	if f == nil {
+		return ""
+	}
+	if f.Toolchain != nil && strings.HasPrefix(f.Toolchain.Name, "go1") {
+		return f.Toolchain.Name
+	}
+	if f.Go != nil {
+		return "go" + f.Go.Version
+	}
+	return ""
+}
+
 // isLocalPackageWithPrefix checks if the import is a local package using the given prefix
 func isLocalPackageWithPrefix(impName string, prefix string) bool {
 	if prefix == "" {
@@ -122,3 +122,58 @@ func TestIsLocalPackageWithPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestModuleGoVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string
+		want  string
+	}{
+		{"go directive", "module example.com/m\n\ngo 1.21\n", "go1.21"},
+		{"go directive with patch", "module example.com/m\n\ngo 1.22.3\n", "go1.22.3"},
+		{"toolchain wins", "module example.com/m\n\ngo 1.21\n\ntoolchain go1.23.4\n", "go1.23.4"},
+		{"no go directive", "module example.com/m\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(tt.gomod), 0644); err != nil {
+				t.Fatal(err)
+			}
+			sub := filepath.Join(dir, "pkg")
+			if err := os.MkdirAll(sub, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if got := moduleGoVersion(filepath.Join(sub, "x.go")); got != tt.want {
+				t.Errorf("moduleGoVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadGoMod_Memoized(t *testing.T) {
+	dir := t.TempDir()
+	goMod := filepath.Join(dir, "go.mod")
+	if err := os.WriteFile(goMod, []byte("module example.com/m\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "x.go")
+	if got := moduleGoVersion(file); got != "go1.21" {
+		t.Fatalf("moduleGoVersion() = %q", got)
+	}
+	first := readGoMod(goMod)
+	if readGoMod(goMod) != first {
+		t.Error("expected the parsed go.mod to be reused")
+	}
+	if goModDirs[file] != goMod {
+		t.Error("expected the go.mod lookup to be memoized")
+	}
+
+	// an edit is picked up
+	if err := os.WriteFile(goMod, []byte("module example.com/m\n\ngo 1.22.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := moduleGoVersion(file); got != "go1.22.1" {
+		t.Errorf("moduleGoVersion() after edit = %q, want go1.22.1", got)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// packageNames memoizes packageName lookups per go.mod and import path
+var packageNames = make(map[string]packageNameResult)
+
+type packageNameResult struct {
+	name string
+	ok   bool
+}
+
+// packageName returns the name declared in the package clause of the
+// package importPath, as seen from the module containing filePath. Std
+// packages are named after their path; other packages are looked up in the
+// module itself, its vendor directory and the module cache. It reports
+// false when the package cannot be found.
+func packageName(importPath, filePath string) (string, bool) {
+	if isStandardPackage(importPath) {
+		return assumedPackageName(importPath), true
+	}
+
+	goModPath := ""
+	if filePath != "" {
+		goModPath = findGoMod(filePath)
+	}
+	key := goModPath + "\x00" + importPath
+	if r, ok := packageNames[key]; ok {
+		return r.name, r.ok
+	}
+
+	var r packageNameResult
+	for _, dir := range packageDirCandidates(importPath, goModPath) {
+		if name, ok := readPackageName(dir); ok {
+			r = packageNameResult{name: name, ok: true}
+			break
+		}
+	}
+	packageNames[key] = r
+	return r.name, r.ok
+}
+
+// packageDirCandidates lists the directories that may hold importPath for
+// the module described by goModPath.
+func packageDirCandidates(importPath, goModPath string) []string {
+	if goModPath == "" {
+		return nil
+	}
+	f := readGoMod(goModPath)
+	if f == nil || f.Module == nil {
+		return nil
+	}
+	modRoot := filepath.Dir(goModPath)
+
+	var dirs []string
+	if rel, ok := pathWithin(importPath, f.Module.Mod.Path); ok {
+		dirs = append(dirs, filepath.Join(modRoot, filepath.FromSlash(rel)))
+	}
+	dirs = append(dirs, filepath.Join(modRoot, "vendor", filepath.FromSlash(importPath)))
+
+	modCache := goModCache()
+	for _, req := range f.Require {
+		rel, ok := pathWithin(importPath, req.Mod.Path)
+		if !ok || modCache == "" {
+			continue
+		}
+		escPath, err := module.EscapePath(req.Mod.Path)
+		if err != nil {
+			continue
+		}
+		escVersion, err := module.EscapeVersion(req.Mod.Version)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(modCache, filepath.FromSlash(escPath)+"@"+escVersion, filepath.FromSlash(rel))
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// pathWithin reports whether importPath is modPath or below it, and
+// returns the remainder.
+func pathWithin(importPath, modPath string) (string, bool) {
+	if importPath == modPath {
+		return "", true
+	}
+	if rel, ok := strings.CutPrefix(importPath, modPath+"/"); ok {
+		return rel, true
+	}
+	return "", false
+}
+
+// goModCache returns the module cache directory without running the go command.
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
+
+// readPackageName reads the package clause of the first non-test go file in dir.
+func readPackageName(dir string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil || f.Name.Name == "documentation" {
+			continue
+		}
+		return f.Name.Name, true
+	}
+	return "", false
+}
+
+// assumedPackageName guesses a package name from its import path the way
+// goimports does: the last element, skipping a major version suffix and
+// dropping a "go-" prefix and anything after the first character that
+// cannot appear in an identifier.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if isMajorSuffix(base) {
+		if dir := path.Dir(importPath); dir != "." {
+			base = path.Base(dir)
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r >= 0x80)
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// isMajorSuffix reports whether elem looks like "v2", "v3", ...
+func isMajorSuffix(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' || elem == "v1" {
+		return false
+	}
+	for _, r := range elem[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAssumedPackageName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                         "fmt",
+		"net/http":                    "http",
+		"github.com/foo/bar/v2":       "bar",
+		"github.com/mattn/go-sqlite3": "sqlite3",
+		"gopkg.in/yaml.v3":            "yaml",
+		"k8s.io/api/core/v1":          "v1",
+	}
+	for path, want := range tests {
+		if got := assumedPackageName(path); got != want {
+			t.Errorf("assumedPackageName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	root := t.TempDir()
+	modCache := t.TempDir()
+	t.Setenv("GOMODCACHE", modCache)
+
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.21\n\nrequire github.com/Foo/bar v1.2.0\n")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(root, "internal", "util-pkg", "util.go"), "package util\n")
+	writeFile(t, filepath.Join(root, "vendor", "example.org", "vend", "v.go"), "package vendored\n")
+	writeFile(t, filepath.Join(modCache, "github.com", "!foo", "bar@v1.2.0", "sub", "doc_test.go"), "package sub_test\n")
+	writeFile(t, filepath.Join(modCache, "github.com", "!foo", "bar@v1.2.0", "sub", "sub.go"), "package barsub\n")
+
+	file := filepath.Join(root, "main.go")
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"strings", "strings", true},
+		{"example.com/app/internal/util-pkg", "util", true},
+		{"example.org/vend", "vendored", true},
+		{"github.com/Foo/bar/sub", "barsub", true},
+		{"github.com/unknown/pkg", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := packageName(tt.path, file)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("packageName(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
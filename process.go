@@ -2,14 +2,16 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
@@ -22,22 +24,58 @@ func isGoFile(f os.FileInfo) bool {
 	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
 }
 
-// walkDir walks through a path, processing all go files recursively in a directory
+// walkDir walks through a path, processing all go files recursively in a directory.
+// A failing file does not stop the walk; all errors are returned joined.
 func walkDir(path string) error {
-	return filepath.Walk(
+	var errs []error
+	err := walkGoFiles(path, func(path string) error {
+		if _, err := processFile(path, nil, os.Stdout); err != nil {
+			errs = append(errs, fileError(path, err))
+		}
+		return nil
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// fileError prefixes err with the file it occurred in
+func fileError(path string, err error) error {
+	if err == nil || errors.Is(err, errNotSorted) {
+		// already reported by path in check mode
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// walkGoFiles calls fn for every go file below path. Files and directories
+// that cannot be read are recorded as failed and skipped; their errors are
+// returned joined once the walk is done.
+func walkGoFiles(path string, fn func(path string) error) error {
+	var errs []error
+	err := filepath.Walk(
 		path,
 		func(path string, f os.FileInfo, err error) error {
-			if err == nil && isGoFile(f) {
-				_, err = processFile(path, nil, os.Stdout)
+			if err != nil {
+				errs = append(errs, err)
+				summary.record(path, outcomeFailed, err)
+				if f != nil && f.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isGoFile(f) {
+				return fn(path)
 			}
-			return err
+			return nil
 		},
 	)
+	return errors.Join(append(errs, err)...)
 }
 
 // processFile reads a file and processes the content, then checks if they're equal.
-func processFile(filename string, in io.Reader, out io.Writer) ([]byte, error) {
-	log.Printf("processing %v\n", filename)
+func processFile(filename string, in io.Reader, out io.Writer) (_ []byte, err error) {
+	debugf("processing %v", filename)
+	outcome := outcomeUnchanged
+	defer func() { summary.record(filename, outcome, fileError(filename, err)) }()
 
 	if in == nil {
 		f, err := os.Open(filename)
@@ -53,13 +91,57 @@ func processFile(filename string, in io.Reader, out io.Writer) ([]byte, error) {
 		return nil, err
 	}
 
-	res, err := process(src, filename)
+	if runCache != nil && runCache.isSorted(filename, src) {
+		debugf("file is known to be sorted")
+		outcome = outcomeSkipped
+		return src, nil
+	}
+
+	start := time.Now()
+	res, stats, err := processWithStats(src, filename)
 	if err != nil {
+		logger.Debug("failed to process file", "path", filename, "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	logger.Debug("processed file",
+		"path", filename,
+		"module", stats.module,
+		"duration", time.Since(start),
+		"imports", stats.imports,
+		"groups", stats.groups,
+		"changed", !bytes.Equal(src, res),
+	)
+	for _, warning := range stats.warnings {
+		logger.Warn(warning, "path", filename)
+	}
+	if err := reportViolations(filename, stats.violations); err != nil {
+		// an unsorted file is still listed in check mode
+		if !bytes.Equal(src, res) {
+			_, _ = fmt.Fprintln(out, filename)
+		}
 		return nil, err
 	}
+	if *dryRun {
+		for _, rewrite := range stats.rewritten {
+			_, _ = fmt.Fprintf(out, "%s: %s\n", filename, rewrite)
+		}
+		if len(stats.rewritten) > 0 {
+			outcome = outcomeChanged
+		}
+		return res, nil
+	}
+	// files with diagnostics are not skipped next time, to report them again.
+	// With -add-missing the result also depends on the other files of the
+	// package, which the cache entry does not cover.
+	cacheable := runCache != nil && !*addMissing && len(stats.warnings) == 0 && len(stats.violations) == 0
 
 	if !bytes.Equal(src, res) {
 		// formatting has changed
+		outcome = outcomeChanged
+		if *check {
+			_, _ = fmt.Fprintln(out, filename)
+			return res, fmt.Errorf("%s: %w", filename, errNotSorted)
+		}
 		if *list {
 			_, _ = fmt.Fprintln(out, string(res))
 		}
@@ -73,12 +155,18 @@ func processFile(filename string, in io.Reader, out io.Writer) ([]byte, error) {
 			if err := os.WriteFile(filename, res, mode); err != nil {
 				return nil, err
 			}
+			if cacheable {
+				runCache.markSorted(filename, res)
+			}
 		}
 		if !*list && !*write {
 			return res, nil
 		}
 	} else {
-		log.Println("file has not been changed")
+		debugf("file has not been changed")
+		if cacheable {
+			runCache.markSorted(filename, src)
+		}
 	}
 
 	return res, nil
@@ -88,13 +176,40 @@ func processFile(filename string, in io.Reader, out io.Writer) ([]byte, error) {
 func closeFile(file *os.File) {
 	err := file.Close()
 	if err != nil {
-		log.Println("could not close file")
+		warnf("could not close file")
 	}
 }
 
 // process processes the source of a file, categorising the imports
 // filePath is used to detect the local module path for the file
 func process(src []byte, filePath string) (output []byte, err error) {
+	output, _, err = processWithStats(src, filePath)
+	return output, err
+}
+
+// processStats describes what process did to a file, for logging
+type processStats struct {
+	module     string // local prefix used for the file
+	imports    int
+	groups     int               // non-empty import groups
+	warnings   []string          // see impManager.warnf
+	violations []importViolation // see impManager.checkDenied
+	rewritten  []string          // import paths changed by -rewrite
+}
+
+// processWithStats is process also reporting processStats. It takes the
+// fast path of splicing only the import section when possible, see
+// fastProcess, and otherwise rewrites the full decorated syntax tree.
+func processWithStats(src []byte, filePath string) (output []byte, stats processStats, err error) {
+	if output, stats, ok := fastProcess(src, filePath); ok {
+		return output, stats, nil
+	}
+	return processDST(src, filePath)
+}
+
+// processDST processes a file by reprinting its full decorated syntax tree,
+// which keeps comments around the imports in place
+func processDST(src []byte, filePath string) (output []byte, stats processStats, err error) {
 	var (
 		fileSet          = token.NewFileSet()
 		convertedImports *impManager
@@ -103,32 +218,94 @@ func process(src []byte, filePath string) (output []byte, err error) {
 
 	node, err = decorator.ParseFile(fileSet, "", src, parser.ParseComments)
 	if err != nil {
-		return nil, err
-	}
-
-	// Determine local prefix for this file
-	fileLocalPrefix := *localPrefix
-	if fileLocalPrefix == "" && filePath != "" {
-		// Auto-detect module path from file location
-		fileLocalPrefix = findModulePath(filePath)
+		// The import section may still be valid; if so, sort just that
+		output, stats, spliceErr := spliceImports(src, filePath)
+		if spliceErr != nil {
+			return nil, stats, err
+		}
+		debugf("syntax error outside the imports, only rewriting the import block: %v", err)
+		return output, stats, nil
 	}
 
-	convertedImports, err = convertImportsToSlice(node, fileLocalPrefix)
+	stats.module = fileLocalPrefix(filePath)
+	std := standardPackagesFor(moduleGoVersion(filePath))
+	convertedImports, err = convertImportsToSliceWithStd(node, stats.module, std)
 	if err != nil {
-		return nil, err
+		return nil, stats, err
 	}
-	if convertedImports.countImports() == 0 {
-		return src, nil
+	renames := adjustImports(convertedImports, src, filePath, std)
+	stats.imports = convertedImports.countImports()
+	stats.warnings = convertedImports.warnings
+	stats.violations = convertedImports.violations
+	stats.rewritten = convertedImports.rewritten
+	if stats.imports == 0 && len(node.Imports) == 0 {
+		return src, stats, nil
+	}
+	renamePackageRefs(node, renames)
+	for _, group := range convertedImports.groups {
+		if group.countImports() > 0 {
+			stats.groups++
+		}
 	}
 
 	convertedImports.sortImports()
 	convertedToGo := convertedImports.convertImportsToGo()
 	output, err = replaceImports(convertedToGo, node)
 	if err != nil {
-		return nil, err
+		return nil, stats, err
 	}
 
-	return output, nil
+	return output, stats, nil
+}
+
+// adjustImports applies the changes to the set of imports, such as -prune,
+// -add-missing and removing duplicates, before they are sorted. It returns
+// the package names to rename in the file body.
+func adjustImports(m *impManager, src []byte, filePath string, std map[string]struct{}) map[string]string {
+	if *stripAliases {
+		for _, imp := range m.stripRedundantAliases(filePath) {
+			debugf("%s: removed redundant alias of %s", filePath, imp)
+		}
+	}
+	renames := m.applyCanonicalAliases(config.Aliases, filePath)
+	for from, to := range m.dedupe(filePath, *mergeAliases) {
+		for name, renamed := range renames {
+			if renamed == from {
+				renames[name] = to
+			}
+		}
+		renames[from] = to
+	}
+	if *prune || *addMissing {
+		used, err := referencedPackages(src)
+		if err != nil {
+			debugf("%s: not pruning or adding imports of a file with syntax errors", filePath)
+		} else {
+			// the body refers to renamed imports by their old names
+			used = renameReferences(used, renames)
+			if *prune {
+				for _, path := range m.pruneUnused(used, filePath) {
+					debugf("%s: removed unused import %s", filePath, path)
+				}
+			}
+			if *addMissing {
+				for _, path := range m.addMissing(used, filePath, std) {
+					debugf("%s: added missing import %q", filePath, path)
+				}
+			}
+		}
+	}
+	m.checkDenied(config.Rules, filePath)
+	return renames
+}
+
+// fileLocalPrefix determines the local prefix for a file, auto-detecting the
+// module path from the file location when -local is not set
+func fileLocalPrefix(filePath string) string {
+	if *localPrefix == "" && filePath != "" {
+		return findModulePath(filePath)
+	}
+	return *localPrefix
 }
 
 // replaceImports replaces existing imports and handles multiple import statements
@@ -154,6 +331,9 @@ func replaceImports(newImports []byte, node *dst.File) ([]byte, error) {
 		return nil, err
 	}
 
+	if len(newImports) == 0 {
+		return buf.Bytes(), nil
+	}
 	packageName := node.Name.Name
 	output = bytes.Replace(buf.Bytes(), []byte("package "+packageName), append([]byte("package "+packageName+"\n\n"), newImports...), 1)
 
@@ -163,33 +343,64 @@ func replaceImports(newImports []byte, node *dst.File) ([]byte, error) {
 // convertImportsToSlice parses the file with AST and gets all imports
 // localPrefix is the module prefix to identify local packages
 func convertImportsToSlice(node *dst.File, localPrefix string) (*impManager, error) {
+	return convertImportsToSliceWithStd(node, localPrefix, standardPackages)
+}
+
+// convertImportsToSliceWithStd is convertImportsToSlice classifying
+// standard packages with the given std set
+func convertImportsToSliceWithStd(node *dst.File, localPrefix string, std map[string]struct{}) (*impManager, error) {
 	importCategories := newImpManager()
 
 	for _, importSpec := range node.Imports {
-		impName := importSpec.Path.Value
-		impNameWithoutQuotes := strings.Trim(impName, "\"")
-		locName := importSpec.Name
+		var locImpModel impModel
+		if importSpec.Name != nil {
+			locImpModel.localReference = importSpec.Name.Name
+		}
+		locImpModel.path = importCategories.rewritePath(importSpec.Path.Value)
+
+		importCategories.classify(&locImpModel, localPrefix, std)
+	}
 
+	return importCategories, nil
+}
+
+// convertASTImportsToSlice is convertImportsToSliceWithStd for a plain go/ast file
+func convertASTImportsToSlice(node *ast.File, localPrefix string, std map[string]struct{}) (*impManager, error) {
+	importCategories := newImpManager()
+
+	for _, importSpec := range node.Imports {
 		var locImpModel impModel
-		if locName != nil {
-			locImpModel.localReference = locName.Name
-		}
-		locImpModel.path = impName
-
-		if localPrefix != "" && isLocalPackageWithPrefix(impName, localPrefix) {
-			var group = importCategories.Local()
-			group.append(&locImpModel)
-		} else if isStandardPackage(impNameWithoutQuotes) {
-			var group = importCategories.Standard()
-			group.append(&locImpModel)
-		} else if isSecondPackage(impNameWithoutQuotes) {
-			var group = importCategories.SecondPart()
-			group.append(&locImpModel)
-		} else {
-			var group = importCategories.ThirdPart()
-			group.append(&locImpModel)
+		if importSpec.Name != nil {
+			locImpModel.localReference = importSpec.Name.Name
 		}
+		locImpModel.path = importCategories.rewritePath(importSpec.Path.Value)
+
+		importCategories.classify(&locImpModel, localPrefix, std)
 	}
 
 	return importCategories, nil
 }
+
+// classify appends an import to the group it belongs to
+func (m *impManager) classify(locImpModel *impModel, localPrefix string, std map[string]struct{}) {
+	impName := locImpModel.path
+	impNameWithoutQuotes := strings.Trim(impName, "\"")
+
+	if locImpModel.localReference == "_" && hasSection(GroupBlank) {
+		m.groups[GroupBlank].append(locImpModel)
+	} else if locImpModel.localReference == "." && hasSection(GroupDot) {
+		m.groups[GroupDot].append(locImpModel)
+	} else if localPrefix != "" && isLocalPackageWithPrefix(impName, localPrefix) {
+		var group = m.Local()
+		group.append(locImpModel)
+	} else if isStandardPackageIn(std, impNameWithoutQuotes) {
+		var group = m.Standard()
+		group.append(locImpModel)
+	} else if isSecondPackage(impNameWithoutQuotes) {
+		var group = m.SecondPart()
+		group.append(locImpModel)
+	} else {
+		var group = m.ThirdPart()
+		group.append(locImpModel)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"go/parser"
 	"go/token"
 	"os"
@@ -302,9 +303,9 @@ func TestProcessFile_InvalidSource(t *testing.T) {
 	resetStringFlag(t, localPrefix)
 	*localPrefix = "github.com/myorg/myrepo"
 
-	// Missing closing brace makes this unparseable.
+	// Missing closing paren makes even the import section unparseable.
 	reader := strings.NewReader(`package main
-import "fmt"
+import ("fmt"
 func main() { fmt.Println("Hello"
 `)
 	defer func() {
@@ -537,3 +538,111 @@ func resetBoolFlag(t *testing.T, ptr *bool) {
 	prev := *ptr
 	t.Cleanup(func() { *ptr = prev })
 }
+
+func TestProcessFile_CheckMode(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	resetBoolFlag(t, check)
+	resetBoolFlag(t, write)
+	*localPrefix = "github.com/myorg/myrepo"
+	*check = true
+	*write = true
+
+	src := `package main
+
+import (
+	"os"
+	"fmt"
+)
+`
+	fp := filepath.Join(t.TempDir(), "in.go")
+	if err := os.WriteFile(fp, []byte(src), 0644); err != nil {
+		t.Fatalf("write tmp file: %v", err)
+	}
+
+	var out bytes.Buffer
+	_, err := processFile(fp, nil, &out)
+	if !errors.Is(err, errNotSorted) {
+		t.Fatalf("expected errNotSorted, got: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != fp {
+		t.Errorf("expected check output to list %s, got: %q", fp, out.String())
+	}
+	// Check mode never writes, even with -w.
+	if disk, _ := os.ReadFile(fp); string(disk) != src {
+		t.Errorf("file on disk should not change in check mode, got:\n%s", disk)
+	}
+}
+
+func TestProcess_ModuleGoVersion(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	src := `package main
+
+import (
+	"github.com/external/lib"
+	"iter"
+	"fmt"
+)
+`
+	for _, tt := range []struct {
+		goVersion string
+		want      string
+	}{
+		// iter was added in go1.23; older modules see it as third-party.
+		{"1.22", "import (\n\t\"fmt\"\n\n\t\"github.com/external/lib\"\n\t\"iter\"\n)"},
+		{"1.23", "import (\n\t\"fmt\"\n\t\"iter\"\n\n\t\"github.com/external/lib\"\n)"},
+	} {
+		t.Run(tt.goVersion, func(t *testing.T) {
+			dir := t.TempDir()
+			gomod := "module example.com/m\n\ngo " + tt.goVersion + "\n"
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := process([]byte(src), filepath.Join(dir, "main.go"))
+			if err != nil {
+				t.Fatalf("process: %v", err)
+			}
+			if !strings.Contains(string(out), tt.want) {
+				t.Errorf("expected %q in:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestProcessFile_SyntaxErrorAfterImports(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	// A half-written function body must not block import sorting.
+	reader := strings.NewReader(`package main
+
+// doc comment
+import (
+	"os"
+	"github.com/myorg/myrepo/pkg"
+	"fmt"
+)
+
+func main() { fmt.Println("Hello"
+`)
+	want := `package main
+
+// doc comment
+import (
+	"fmt"
+	"os"
+
+	"github.com/myorg/myrepo/pkg"
+)
+
+func main() { fmt.Println("Hello"
+`
+	output, err := processFile("", reader, os.Stdout)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if string(output) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, string(output))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// referencedPackages returns the names used as the package of a qualified
+// identifier (pkg.Name) in src, with the selected names. Names shadowed by
+// a declaration in the file are resolved by the parser and not reported.
+func referencedPackages(src []byte) (map[string]map[string]bool, error) {
+	node, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+			if used[ident.Name] == nil {
+				used[ident.Name] = make(map[string]bool)
+			}
+			used[ident.Name][sel.Sel.Name] = true
+		}
+		return true
+	})
+	return used, nil
+}
+
+// renameReferences maps the package names referenced in used through
+// renames, so they match the imports once renamed.
+func renameReferences(used map[string]map[string]bool, renames map[string]string) map[string]map[string]bool {
+	if len(renames) == 0 {
+		return used
+	}
+	renamed := make(map[string]map[string]bool, len(used))
+	for name, sels := range used {
+		if to, ok := renames[name]; ok {
+			name = to
+		}
+		if renamed[name] == nil {
+			renamed[name] = make(map[string]bool)
+		}
+		for sel := range sels {
+			renamed[name][sel] = true
+		}
+	}
+	return renamed
+}
+
+// importName returns the name an import is referenced by in the file. It
+// reports false for blank, dot and cgo imports, which are never unused,
+// and for packages whose name cannot be determined.
+func importName(imp *impModel, filePath string) (string, bool) {
+	path := strings.Trim(imp.path, "\"")
+	switch {
+	case imp.localReference == "_" || imp.localReference == ".":
+		return "", false
+	case imp.localReference != "":
+		return imp.localReference, true
+	case path == "C":
+		return "", false
+	}
+	name, ok := packageName(path, filePath)
+	if !ok {
+		debugf("%s: cannot determine the package name of %s, keeping it", filePath, imp.path)
+	}
+	return name, ok
+}
+
+// pruneUnused drops the imports whose name is not in used and returns
+// their paths. Imports whose name is unknown are kept.
+func (m *impManager) pruneUnused(used map[string]map[string]bool, filePath string) []string {
+	var removed []string
+	for _, group := range m.groups {
+		kept := group.models[:0]
+		for _, imp := range group.models {
+			name, ok := importName(imp, filePath)
+			if ok && used[name] == nil {
+				removed = append(removed, imp.path)
+				continue
+			}
+			kept = append(kept, imp)
+		}
+		group.models = kept
+	}
+	return removed
+}
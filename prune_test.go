@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProcess_Prune(t *testing.T) {
+	resetBoolFlag(t, prune)
+	*prune = true
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "example.com/app"
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(root, "internal", "util-pkg", "util.go"), "package util\n")
+	file := filepath.Join(root, "main.go")
+
+	src := `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	str "strconv"
+	_ "embed"
+	. "math"
+	"example.com/app/internal/util-pkg"
+	"github.com/unknown/pkg"
+)
+
+func main() {
+	var strings = []string{"shadowed"}
+	fmt.Println(strings, str.Itoa(1), Pi, util.X)
+}
+`
+	want := `package main
+
+import (
+	_ "embed"
+	"fmt"
+	. "math"
+	str "strconv"
+
+	"github.com/unknown/pkg"
+
+	"example.com/app/internal/util-pkg"
+)
+
+func main() {
+	var strings = []string{"shadowed"}
+	fmt.Println(strings, str.Itoa(1), Pi, util.X)
+}
+`
+	for name, fn := range map[string]func([]byte, string) ([]byte, processStats, error){
+		"fast": processWithStats,
+		"dst":  processDST,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, _, err := fn([]byte(src), file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestProcess_PruneAll(t *testing.T) {
+	resetBoolFlag(t, prune)
+	*prune = true
+	src := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {}\n"
+	want := "package main\n\nfunc main() {}\n"
+	for name, fn := range map[string]func([]byte, string) ([]byte, processStats, error){
+		"fast": processWithStats,
+		"dst":  processDST,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, _, err := fn([]byte(src), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, want)
+			}
+		})
+	}
+}
+
+func TestProcess_PruneCanonicalAlias(t *testing.T) {
+	resetBoolFlag(t, prune)
+	*prune = true
+	setConfig(t, &Config{Aliases: map[string]string{"k8s.io/api/core/v1": "corev1"}})
+	src := `package main
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+)
+
+func H() { fmt.Println(v1.Pod{}) }
+`
+	want := `package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func H() { fmt.Println(corev1.Pod{}) }
+`
+	got, err := process([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathRewrite replaces the import path prefix old with new. With anyMajor,
+// old is a module path without major version suffix and also matches its
+// "/vN" paths, see migrateMajorMain.
+type pathRewrite struct {
+	old, new string
+	anyMajor bool
+}
+
+// pathRewrites is the repeatable -rewrite flag
+type pathRewrites []pathRewrite
+
+// rewrites holds the -rewrite flags of the run
+var rewrites pathRewrites
+
+func (r *pathRewrites) String() string {
+	if r == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*r))
+	for _, rw := range *r {
+		if rw.anyMajor {
+			parts = append(parts, rw.old+"/*="+rw.new)
+			continue
+		}
+		parts = append(parts, rw.old+"="+rw.new)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *pathRewrites) Set(value string) error {
+	old, new, ok := strings.Cut(value, "=")
+	old, new = strings.TrimSuffix(old, "/"), strings.TrimSuffix(new, "/")
+	if !ok || old == "" || new == "" {
+		return fmt.Errorf("invalid rewrite %q, want old=new", value)
+	}
+	*r = append(*r, pathRewrite{old: old, new: new})
+	return nil
+}
+
+// apply rewrites a quoted import path with the first matching prefix and
+// reports whether it changed.
+func (r pathRewrites) apply(quoted string) (string, bool) {
+	importPath, err := strconv.Unquote(quoted)
+	if err != nil {
+		return quoted, false
+	}
+	for _, rw := range r {
+		rest, ok := pathWithin(importPath, rw.old)
+		if !ok {
+			continue
+		}
+		if rw.anyMajor {
+			if major, sub, _ := strings.Cut(rest, "/"); isMajorSuffix(major) {
+				rest = sub
+			}
+		}
+		if rest != "" {
+			rest = "/" + rest
+		}
+		rewritten := rw.new + rest
+		return strconv.Quote(rewritten), rewritten != importPath
+	}
+	return quoted, false
+}
+
+// rewritePath applies the -rewrite flags to an import path before it is
+// classified, recording the change.
+func (m *impManager) rewritePath(quoted string) string {
+	rewritten, ok := rewrites.apply(quoted)
+	if ok {
+		m.rewritten = append(m.rewritten, quoted+" => "+rewritten)
+	}
+	return rewritten
+}
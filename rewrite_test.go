@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setRewrites(t *testing.T, values ...string) {
+	t.Helper()
+	prev := rewrites
+	t.Cleanup(func() { rewrites = prev })
+	rewrites = nil
+	for _, v := range values {
+		if err := rewrites.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPathRewrites_Set(t *testing.T) {
+	var r pathRewrites
+	for _, invalid := range []string{"old", "=new", "old="} {
+		if err := r.Set(invalid); err == nil {
+			t.Errorf("Set(%q) should fail", invalid)
+		}
+	}
+	if err := r.Set("github.com/oldorg/x/=corp.example/x"); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.String(); got != "github.com/oldorg/x=corp.example/x" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestPathRewrites_Apply(t *testing.T) {
+	setRewrites(t, "github.com/oldorg/x=corp.example/x", "github.com/oldorg=corp.example/legacy")
+	tests := map[string]string{
+		`"github.com/oldorg/x"`:       `"corp.example/x"`,
+		`"github.com/oldorg/x/sub"`:   `"corp.example/x/sub"`,
+		`"github.com/oldorg/xy"`:      `"corp.example/legacy/xy"`,
+		`"github.com/oldorgs/x"`:      `"github.com/oldorgs/x"`,
+		`"github.com/other/oldorg/x"`: `"github.com/other/oldorg/x"`,
+	}
+	for in, want := range tests {
+		if got, _ := rewrites.apply(in); got != want {
+			t.Errorf("apply(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestProcessFile_Rewrite(t *testing.T) {
+	setRewrites(t, "github.com/oldorg/x=corp.example/x")
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "corp.example"
+	src := "package main\n\nimport (\n\t\"fmt\"\n\n\tx \"github.com/oldorg/x/sub\"\n\t\"github.com/y/z\"\n)\n"
+	want := "package main\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/y/z\"\n\n\tx \"corp.example/x/sub\"\n)\n"
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	writeFile(t, path, src)
+
+	resetBoolFlag(t, dryRun)
+	*dryRun = true
+	var out bytes.Buffer
+	if _, err := processFile(path, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), path+`: "github.com/oldorg/x/sub" => "corp.example/x/sub"`+"\n"; got != want {
+		t.Errorf("dry run listed %q, want %q", got, want)
+	}
+
+	*dryRun = false
+	got, err := processFile(path, nil, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if onDisk, _ := os.ReadFile(path); string(onDisk) != src {
+		t.Errorf("file should not have been written without -w")
+	}
+}
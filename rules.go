@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Rule denies imports in a set of files
+type Rule struct {
+	// Files are globs of the files the rule applies to, relative to the
+	// config file; "**" matches any number of directories. A rule without
+	// files applies to every file.
+	Files []string       `json:"files,omitempty"`
+	Deny  []DeniedImport `json:"deny"`
+}
+
+// DeniedImport is an import path pattern that must not be imported
+type DeniedImport struct {
+	// Path is an import path, a path.Match pattern, or a path ending in
+	// "/..." which also matches everything below it
+	Path    string `json:"path"`
+	Message string `json:"message,omitempty"`
+}
+
+// importViolation is an import denied by a rule
+type importViolation struct {
+	path    string // import path
+	message string
+}
+
+// matches reports whether importPath is denied.
+func (d DeniedImport) matches(importPath string) bool {
+	if prefix, ok := strings.CutSuffix(d.Path, "/..."); ok {
+		return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+	}
+	ok, _ := path.Match(d.Path, importPath)
+	return ok
+}
+
+// appliesTo reports whether the rule covers the file, given relative to
+// the config file with slashes.
+func (r Rule) appliesTo(file string) bool {
+	if len(r.Files) == 0 {
+		return true
+	}
+	for _, glob := range r.Files {
+		if matchGlob(strings.Split(glob, "/"), strings.Split(file, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path elements against glob elements, where "**"
+// matches zero or more elements.
+func matchGlob(glob, elems []string) bool {
+	if len(glob) == 0 {
+		return len(elems) == 0
+	}
+	if glob[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchGlob(glob[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(glob[0], elems[0]); !ok {
+		return false
+	}
+	return matchGlob(glob[1:], elems[1:])
+}
+
+// checkDenied records the imports denied for filePath by the config rules.
+func (m *impManager) checkDenied(rules []Rule, filePath string) {
+	if len(rules) == 0 {
+		return
+	}
+	file := filepath.ToSlash(filePath)
+	if abs, err := filepath.Abs(filePath); err == nil && config.dir != "" {
+		if rel, err := filepath.Rel(config.dir, abs); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+	}
+	for _, rule := range rules {
+		if !rule.appliesTo(file) {
+			continue
+		}
+		for _, group := range m.groups {
+			for _, imp := range group.models {
+				importPath := strings.Trim(imp.path, "\"")
+				for _, denied := range rule.Deny {
+					if denied.matches(importPath) {
+						m.violations = append(m.violations, importViolation{path: importPath, message: denied.Message})
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func (v importViolation) String() string {
+	if v.message == "" {
+		return fmt.Sprintf("import %q is denied", v.path)
+	}
+	return fmt.Sprintf("import %q is denied: %s", v.path, v.message)
+}
+
+// reportViolations logs the denied imports of a file as structured records.
+// In check mode they are errors and fail the file.
+func reportViolations(filename string, violations []importViolation) error {
+	var errs []error
+	for _, v := range violations {
+		if *check {
+			errs = append(errs, errors.New(v.String()))
+			continue
+		}
+		logger.Warn("denied import", "path", filename, "import", v.path, "message", v.message)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, file string
+		want       bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"pkg/**", "pkg/a/b/main.go", true},
+		{"pkg/**/*_test.go", "pkg/main_test.go", true},
+		{"pkg/**/*_test.go", "pkg/a/main.go", false},
+		{"**/internal/*", "x/internal/a.go", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(strings.Split(tt.glob, "/"), strings.Split(tt.file, "/")); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.glob, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestDeniedImport_Matches(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"io/ioutil", "io/ioutil", true},
+		{"io/ioutil", "io", false},
+		{"corp.example/internal/...", "corp.example/internal", true},
+		{"corp.example/internal/...", "corp.example/internal/db", true},
+		{"corp.example/internal/...", "corp.example/internalx", false},
+		{"github.com/*/errors", "github.com/pkg/errors", true},
+	}
+	for _, tt := range tests {
+		if got := (DeniedImport{Path: tt.pattern}).matches(tt.path); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProcessFile_DeniedImports(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, configFileName), `{"rules": [
+		{"files": ["api/**"], "deny": [{"path": "github.com/pkg/errors", "message": "use errors"}]},
+		{"deny": [{"path": "io/ioutil", "message": "use io and os"}]}
+	]}`)
+	c, err := loadConfig(filepath.Join(root, configFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfig(t, c)
+	src := "package main\n\nimport (\n\t\"io/ioutil\"\n\n\t\"github.com/pkg/errors\"\n)\n"
+	apiFile := filepath.Join(root, "api", "v1", "main.go")
+	otherFile := filepath.Join(root, "cmd", "main.go")
+	writeFile(t, apiFile, src)
+	writeFile(t, otherFile, src)
+
+	resetBoolFlag(t, check)
+	*check = true
+	_, err = processFile(apiFile, nil, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), `import "io/ioutil" is denied: use io and os`) ||
+		!strings.Contains(err.Error(), `import "github.com/pkg/errors" is denied: use errors`) {
+		t.Errorf("unexpected error for %s: %v", apiFile, err)
+	}
+	_, err = processFile(otherFile, nil, &bytes.Buffer{})
+	if err == nil || strings.Contains(err.Error(), "github.com/pkg/errors") || errors.Is(err, errNotSorted) {
+		t.Errorf("unexpected error for %s: %v", otherFile, err)
+	}
+
+	unsorted := filepath.Join(root, "cmd", "unsorted.go")
+	writeFile(t, unsorted, "package main\n\nimport (\n\t\"os\"\n\t\"io/ioutil\"\n)\n")
+	var out bytes.Buffer
+	_, err = processFile(unsorted, nil, &out)
+	if err == nil || !strings.Contains(err.Error(), `import "io/ioutil" is denied`) {
+		t.Errorf("unexpected error for %s: %v", unsorted, err)
+	}
+	if out.String() != unsorted+"\n" {
+		t.Errorf("expected the unsorted file to be listed too, got %q", out.String())
+	}
+
+	*check = false
+	buf := captureLogs(t, "json", false, false)
+	if _, err := processFile(otherFile, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("denied imports should only be reported outside check mode: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"msg":"denied import"`) || !strings.Contains(buf.String(), `"import":"io/ioutil"`) {
+		t.Errorf("missing structured report in:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// runCacheInfo is the on-disk format of a RunCache
+type runCacheInfo struct {
+	Key   string            `json:"key"`
+	Files map[string]string `json:"files"`
+}
+
+// RunCache remembers files that are already sorted, so later runs over an
+// unchanged tree can skip parsing them. Entries are keyed by file content,
+// the file's effective local prefix and its module Go version; the whole
+// cache is dropped when the configuration, tool version or std package set
+// changes.
+type RunCache struct {
+	file  string
+	key   string
+	files map[string]string
+	dirty bool
+}
+
+// openRunCache loads the run cache of the project rooted at root from
+// cacheDir. A missing, unreadable or outdated cache yields an empty one.
+func openRunCache(cacheDir, root, key string) *RunCache {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	sum := sha256.Sum256([]byte(abs))
+	c := &RunCache{
+		file:  filepath.Join(cacheDir, "runs", hex.EncodeToString(sum[:8])+".json"),
+		key:   key,
+		files: make(map[string]string),
+	}
+
+	bs, err := os.ReadFile(c.file)
+	if err != nil {
+		return c
+	}
+	var info runCacheInfo
+	if err := json.Unmarshal(bs, &info); err != nil || info.Key != key {
+		// invalidated: start from scratch and overwrite on save
+		c.dirty = true
+		return c
+	}
+	if info.Files != nil {
+		c.files = info.Files
+	}
+	// Record the use so that `cache prune` keeps this project
+	now := time.Now()
+	_ = os.Chtimes(c.file, now, now)
+	return c
+}
+
+// entryDigest hashes everything that decides the result for one file.
+func (c *RunCache) entryDigest(filename string, src []byte) string {
+	h := sha256.New()
+	h.Write([]byte(fileLocalPrefix(filename)))
+	h.Write([]byte{0})
+	h.Write([]byte(moduleGoVersion(filename)))
+	h.Write([]byte{0})
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isSorted reports whether src is known to be sorted for filename.
+func (c *RunCache) isSorted(filename string, src []byte) bool {
+	if filename == "" {
+		return false
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return false
+	}
+	digest, ok := c.files[abs]
+	return ok && digest == c.entryDigest(filename, src)
+}
+
+// markSorted records that src is sorted for filename.
+func (c *RunCache) markSorted(filename string, src []byte) {
+	if filename == "" {
+		return
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return
+	}
+	digest := c.entryDigest(filename, src)
+	if c.files[abs] != digest {
+		c.files[abs] = digest
+		c.dirty = true
+	}
+}
+
+// save writes the cache back to disk when it has changed.
+func (c *RunCache) save() error {
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	bs, err := json.Marshal(runCacheInfo{Key: c.key, Files: c.files})
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(c.file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := atomicWriteFile(c.file, bs, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// runCacheKey combines everything that invalidates the whole run cache:
+// the effective configuration, the tool version and the std package set.
+func runCacheKey(std map[string]struct{}) string {
+	h := sha256.New()
+	options := fmt.Sprint(*prune, *addMissing, *mergeAliases, *stripAliases)
+	for _, s := range []string{toolVersion(), *localPrefix, *secondPrefix, *preferImports, *sections, options, config.key(), rewrites.String()} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	pkgs := make([]string, 0, len(std))
+	for p := range std {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+	for _, p := range pkgs {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// toolVersion identifies the running sortimport build.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+			version += " " + s.Value
+		}
+	}
+	return version
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCache_SaveAndReopen(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	cacheDir := t.TempDir()
+	root := t.TempDir()
+	file := filepath.Join(root, "a.go")
+	src := []byte("package a\n")
+
+	c := openRunCache(cacheDir, root, "key1")
+	if c.isSorted(file, src) {
+		t.Error("empty cache should not know the file")
+	}
+	c.markSorted(file, src)
+	if err := c.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	c = openRunCache(cacheDir, root, "key1")
+	if !c.isSorted(file, src) {
+		t.Error("expected file to be known after reopen")
+	}
+	if c.isSorted(file, []byte("package b\n")) {
+		t.Error("changed content must not be treated as sorted")
+	}
+
+	// Changing the local prefix changes the effective configuration.
+	*localPrefix = "github.com/other/repo"
+	if c.isSorted(file, src) {
+		t.Error("different local prefix must not be treated as sorted")
+	}
+}
+
+func TestRunCache_KeyInvalidates(t *testing.T) {
+	cacheDir := t.TempDir()
+	root := t.TempDir()
+	file := filepath.Join(root, "a.go")
+	src := []byte("package a\n")
+
+	c := openRunCache(cacheDir, root, "key1")
+	c.markSorted(file, src)
+	if err := c.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	c = openRunCache(cacheDir, root, "key2")
+	if c.isSorted(file, src) {
+		t.Error("cache with a different key must be discarded")
+	}
+}
+
+func TestRunCacheKey(t *testing.T) {
+	resetStringFlag(t, secondPrefix)
+	std := map[string]struct{}{"fmt": {}, "os": {}}
+
+	base := runCacheKey(std)
+	if base != runCacheKey(map[string]struct{}{"os": {}, "fmt": {}}) {
+		t.Error("key should not depend on map order")
+	}
+	if base == runCacheKey(map[string]struct{}{"fmt": {}, "os": {}, "iter": {}}) {
+		t.Error("key should change with the std package set")
+	}
+	*secondPrefix = "github.com/myorg"
+	if base == runCacheKey(std) {
+		t.Error("key should change with the configuration")
+	}
+}
+
+func TestProcessFile_SkipsCachedFile(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	prev := runCache
+	t.Cleanup(func() { runCache = prev })
+	runCache = openRunCache(t.TempDir(), t.TempDir(), "key")
+
+	fp := filepath.Join(t.TempDir(), "in.go")
+	// Unparseable content marked as sorted proves process was skipped.
+	src := []byte("package main\nfunc {")
+	if err := os.WriteFile(fp, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCache.markSorted(fp, src)
+
+	res, err := processFile(fp, nil, os.Stdout)
+	if err != nil {
+		t.Fatalf("expected cached file to be skipped, got: %v", err)
+	}
+	if string(res) != string(src) {
+		t.Errorf("expected source to be returned unchanged, got:\n%s", res)
+	}
+}
+
+func TestProcessFile_AddMissingNotCached(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+	resetBoolFlag(t, addMissing)
+	*addMissing = true
+
+	prev := runCache
+	t.Cleanup(func() { runCache = prev })
+	runCache = openRunCache(t.TempDir(), t.TempDir(), "key")
+
+	dir := t.TempDir()
+	t.Cleanup(func() { delete(packageDecls, dir) })
+	file := filepath.Join(dir, "a.go")
+	writeFile(t, file, "package p\n\nvar _ = strings.ToUpper\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package p\n\nvar strings struct{ ToUpper func(string) string }\n")
+	if _, err := processFile(file, nil, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the declaration in b.go goes away, as in a later run
+	writeFile(t, filepath.Join(dir, "b.go"), "package p\n")
+	delete(packageDecls, dir)
+	res, err := processFile(file, nil, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(res), `"strings"`) {
+		t.Errorf("expected strings to be added, got:\n%s", res)
+	}
+}
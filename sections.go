@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// sectionNames maps the names used by -sections to import groups
+var sectionNames = map[string]int{
+	"std":    GroupStandard,
+	"third":  GroupThird,
+	"second": GroupSecond,
+	"local":  GroupLocal,
+	"blank":  GroupBlank,
+	"dot":    GroupDot,
+}
+
+// sectionOrder is the order the import groups are printed in. Blank and
+// dot imports only get their own group when it is listed.
+var sectionOrder = []int{GroupStandard, GroupThird, GroupSecond, GroupLocal}
+
+// parseSections parses a comma-separated list of section names. Every
+// section but blank and dot must be listed exactly once.
+func parseSections(value string) ([]int, error) {
+	var order []int
+	for _, name := range strings.Split(value, ",") {
+		group, ok := sectionNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown import section %q, want std, third, second, local, blank or dot", name)
+		}
+		if slices.Contains(order, group) {
+			return nil, fmt.Errorf("import section %q listed twice", name)
+		}
+		order = append(order, group)
+	}
+	for _, group := range []int{GroupStandard, GroupThird, GroupSecond, GroupLocal} {
+		if !slices.Contains(order, group) {
+			return nil, fmt.Errorf("import sections %q must include std, third, second and local", value)
+		}
+	}
+	return order, nil
+}
+
+// hasSection reports whether the group is printed as its own section
+func hasSection(group int) bool {
+	return slices.Contains(sectionOrder, group)
+}
@@ -0,0 +1,95 @@
+package main
+
+import "testing"
+
+func TestParseSections(t *testing.T) {
+	for _, invalid := range []string{"std,third,second", "std,third,second,local,std", "std,third,second,local,other"} {
+		if _, err := parseSections(invalid); err == nil {
+			t.Errorf("parseSections(%q) should fail", invalid)
+		}
+	}
+	order, err := parseSections("blank, std,third,second,local,dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order[0] != GroupBlank || order[5] != GroupDot {
+		t.Errorf("unexpected order %v", order)
+	}
+}
+
+func TestProcess_BlankAndDotSections(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg"
+	src := `package main
+
+import (
+	_ "embed"
+	"fmt"
+	. "github.com/onsi/gomega"
+	_ "github.com/lib/pq"
+	"github.com/x/y"
+	_ "github.com/myorg/plugin"
+)
+`
+	tests := []struct {
+		sections, want string
+	}{
+		{"std,third,second,local", `package main
+
+import (
+	_ "embed"
+	"fmt"
+
+	_ "github.com/lib/pq"
+	. "github.com/onsi/gomega"
+	"github.com/x/y"
+
+	_ "github.com/myorg/plugin"
+)
+`},
+		{"std,third,second,local,dot,blank", `package main
+
+import (
+	"fmt"
+
+	"github.com/x/y"
+
+	. "github.com/onsi/gomega"
+
+	_ "embed"
+	_ "github.com/lib/pq"
+	_ "github.com/myorg/plugin"
+)
+`},
+		{"blank,std,third,second,local", `package main
+
+import (
+	_ "embed"
+	_ "github.com/lib/pq"
+	_ "github.com/myorg/plugin"
+
+	"fmt"
+
+	. "github.com/onsi/gomega"
+	"github.com/x/y"
+)
+`},
+	}
+	prev := sectionOrder
+	t.Cleanup(func() { sectionOrder = prev })
+	for _, tt := range tests {
+		t.Run(tt.sections, func(t *testing.T) {
+			var err error
+			if sectionOrder, err = parseSections(tt.sections); err != nil {
+				t.Fatal(err)
+			}
+			got, err := process([]byte(src), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
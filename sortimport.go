@@ -1,35 +1,67 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"runtime"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 )
 
 var (
 	list             = flag.Bool("l", false, "write results to stdout")
 	write            = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	check            = flag.Bool("check", false, "list files whose imports are not sorted and exit non-zero")
 	localPrefix      = flag.String("local", "", "put imports beginning with this string after 3rd-party packages; comma-separated list")
 	secondPrefix     = flag.String("second", "", "put imports beginning with this string after 3rd-party packages; comma-separated list")
 	updateCache      = flag.Bool("u", false, "update the standard package cache for current Go version")
+	gitChanged       = flag.Bool("git-changed", false, "only process .go files changed in git (against HEAD, including untracked files)")
+	gitSince         = flag.String("since", "", "only process .go files that differ from this git revision")
+	gitStaged        = flag.Bool("staged", false, "only process .go files staged in the git index")
+	gitIndex         = flag.Bool("index", false, "sort staged .go blobs directly in the git index, keeping unstaged changes")
+	indexWorktree    = flag.Bool("index-worktree", false, "with -index, also update worktree files that match the staged content")
+	watchMode        = flag.Bool("watch", false, "keep running and re-process go files when they change")
+	watchInterval    = flag.Duration("watch-interval", 500*time.Millisecond, "polling interval for -watch")
+	useRunCache      = flag.Bool("run-cache", true, "skip files already known to be sorted in a previous run")
+	logFormat        = flag.String("log-format", "text", "log format: text or json")
+	cacheDirFlag     = flag.String("cache-dir", "", "cache directory (default $"+cacheDirEnv+", else the user cache directory)")
+	dryRun           = flag.Bool("dry-run", false, "list the import paths -rewrite would change per file, without changing anything")
+	sections         = flag.String("sections", "std,third,second,local", "order of the import sections; add blank and dot to give _ and . imports their own section, e.g. std,third,second,local,blank")
+	configFlag       = flag.String("config", "", "config file (default the nearest "+configFileName+" from the working directory)")
+	prune            = flag.Bool("prune", false, "remove imports that are not referenced in the file")
+	addMissing       = flag.Bool("add-missing", false, "add std imports for unresolved references such as strings.Builder")
+	stripAliases     = flag.Bool("strip-aliases", false, "remove import aliases equal to the package name, e.g. fmt \"fmt\"")
+	mergeAliases     = flag.Bool("merge-aliases", false, "import a path imported under several names only once, renaming its usages")
+	preferImports    = flag.String("prefer", "", "import paths -add-missing prefers when a name is ambiguous, e.g. crypto/rand; comma-separated list")
 	verbose          bool // verbose logging
+	quiet            bool // only log errors
 	standardPackages = make(map[string]struct{})
 	cacheManager     *CacheManager
+	runCache         *RunCache
+	errNotSorted     = errors.New("imports are not sorted")
 )
 
+// subcommands maps subcommand names to their entry points.
+var subcommands = map[string]func(args []string) error{
+	"install-hook":  installHookMain,
+	"cache":         cacheMain,
+	"migrate-major": migrateMajorMain,
+}
+
 // main is the entry point of the program
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
 	err := goImportsSortMain()
 	if err != nil {
-		log.Fatalln(err)
+		errorf("%v", err)
+		os.Exit(1)
 	}
 }
 
@@ -37,22 +69,57 @@ func main() {
 func goImportsSortMain() error {
 	flag.Usage = func() {
 		_, _ = fmt.Fprintf(os.Stderr, "usage: goimportssort [flags] [path ...]\n")
+		_, _ = fmt.Fprintf(os.Stderr, "       goimportssort install-hook [-uninstall] [dir]\n")
+		_, _ = fmt.Fprintf(os.Stderr, "       goimportssort [-cache-dir dir] cache list|info|prune [-days N]|clear\n")
+		_, _ = fmt.Fprintf(os.Stderr, "       goimportssort migrate-major <module> <vN> [flags] [path ...]\n")
 		flag.PrintDefaults()
 		os.Exit(2)
 	}
-	paths := parseFlags()
+	if cmd, args, ok := findSubcommand(os.Args[1:]); ok {
+		return cmd(args)
+	}
+	return sortMain(os.Args[1:])
+}
 
-	if verbose {
-		log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	} else {
-		log.SetOutput(io.Discard)
+// findSubcommand reports whether args name a subcommand and returns it with
+// its arguments. -cache-dir may precede the subcommand name, as in
+// "sortimport -cache-dir dir cache list".
+func findSubcommand(args []string) (func([]string) error, []string, bool) {
+	fs := flag.NewFlagSet("sortimport", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cacheDir := fs.String("cache-dir", "", "")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		return nil, nil, false
+	}
+	cmd, ok := subcommands[fs.Arg(0)]
+	if !ok {
+		return nil, nil, false
+	}
+	if *cacheDir != "" {
+		*cacheDirFlag = *cacheDir
 	}
+	return cmd, fs.Args()[1:], true
+}
 
-	// Initialize cache manager
+// sortMain parses the flags in args and processes the paths that follow
+func sortMain(args []string) error {
+	paths := parseFlags(args)
+
+	if err := setupLogging(os.Stderr, *logFormat, verbose, quiet); err != nil {
+		return err
+	}
+	if err := setupConfig(*configFlag); err != nil {
+		return err
+	}
 	var err error
+	if sectionOrder, err = parseSections(*sections); err != nil {
+		return err
+	}
+
+	// Initialize cache manager
 	cacheManager, err = newCacheManager()
 	if err != nil {
-		log.Printf("warning: failed to initialize cache manager: %v\n", err)
+		warnf("failed to initialize cache manager: %v", err)
 	}
 
 	// Handle cache update flag
@@ -63,19 +130,65 @@ func goImportsSortMain() error {
 		if err := cacheManager.update(); err != nil {
 			return fmt.Errorf("failed to update cache: %w", err)
 		}
-		fmt.Printf("Cache updated for %s\n", cacheManager.version)
+		infof("Cache updated for %s", cacheManager.version)
 		return nil
 	}
 
 	if *localPrefix == "" {
-		log.Println("no prefix found, using module name")
+		debugf("no prefix found, using module name")
 
 		moduleName := getModuleName()
 		if moduleName != "" {
 			localPrefix = &moduleName
 		} else {
-			log.Println("module name not found. skipping localprefix")
+			debugf("module name not found. skipping localprefix")
+		}
+	}
+
+	if *gitIndex {
+		if err := loadStandardPackages(); err != nil {
+			return fmt.Errorf("failed to load standard packages: %w", err)
+		}
+		if len(paths) == 0 {
+			paths = []string{"."}
 		}
+		summary = newRunSummary()
+		var errs []error
+		for _, path := range paths {
+			changed, err := formatIndex(stripGoEllipsis(path), *check, *indexWorktree)
+			for _, name := range changed {
+				if *check {
+					_, _ = fmt.Fprintln(os.Stdout, name)
+				} else {
+					infof("updated index entry for %s", name)
+				}
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if reportErr := summary.report(); reportErr != nil {
+			return reportErr
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		if *check && summary.changed > 0 {
+			return fmt.Errorf("%d staged files: %w", summary.changed, errNotSorted)
+		}
+		return nil
+	}
+
+	if gitMode() {
+		files, err := gitChangedPaths(paths)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			infof("no changed go files")
+			return nil
+		}
+		paths = files
 	}
 
 	if len(paths) == 0 {
@@ -87,44 +200,70 @@ func goImportsSortMain() error {
 		return fmt.Errorf("failed to load standard packages: %w", err)
 	}
 
-	return processPaths(paths, os.Stdout)
+	if *useRunCache && cacheManager != nil && !cacheManager.memoryOnly {
+		runCache = openRunCache(cacheManager.cacheDir, ".", runCacheKey(standardPackages))
+		defer func() {
+			if err := runCache.save(); err != nil {
+				warnf("failed to write run cache: %v", err)
+			}
+		}()
+	}
+
+	if *watchMode {
+		if err := processPaths(paths, os.Stdout); err != nil {
+			errorf("%v", err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return newWatcher(paths, *watchInterval, os.Stdout).run(ctx)
+	}
+
+	summary = newRunSummary()
+	err = processPaths(paths, os.Stdout)
+	if reportErr := summary.report(); reportErr != nil {
+		return reportErr
+	}
+	if errors.Is(err, errNotSorted) {
+		return fmt.Errorf("%d files: %w", summary.changed, errNotSorted)
+	}
+	return err
 }
 
 // processPaths processes each path (file or directory) sequentially.
 // It continues on error so a single bad file does not abort the batch,
-// returning the first error encountered (if any).
+// returning all errors encountered joined together (if any).
 // Go-style "..." patterns are accepted: "./...", "pkg/...", "..." are
 // expanded to their containing directory and walked recursively.
 func processPaths(paths []string, out io.Writer) error {
-	var firstErr error
+	var errs []error
 	for _, path := range paths {
 		path = stripGoEllipsis(path)
 		dir, statErr := os.Stat(path)
 		if statErr != nil {
-			if firstErr == nil {
-				firstErr = statErr
-			}
-			log.Printf("error stating %s: %v\n", path, statErr)
+			errs = append(errs, statErr)
+			summary.record(path, outcomeFailed, statErr)
 			continue
 		}
 		if dir.IsDir() {
-			if err := walkDir(path); err != nil && firstErr == nil {
-				firstErr = err
+			if err := walkDir(path); err != nil {
+				errs = append(errs, err)
 			}
 			continue
 		}
-		if _, err := processFile(path, nil, out); err != nil && firstErr == nil {
-			firstErr = err
+		if _, err := processFile(path, nil, out); err != nil {
+			errs = append(errs, fileError(path, err))
 		}
 	}
-	return firstErr
+	return errors.Join(errs...)
 }
 
 // parseFlags parses command line flags and returns the paths to process.
 // It's a var so that custom implementations can replace it in other files.
-var parseFlags = func() []string {
+var parseFlags = func(args []string) []string {
 	flag.BoolVar(&verbose, "v", false, "verbose logging")
-	flag.Parse()
+	flag.BoolVar(&quiet, "q", false, "only log errors")
+	flag.Var(&rewrites, "rewrite", "rewrite import paths with prefix old to new, `old=new`; repeatable")
+	_ = flag.CommandLine.Parse(args) // exits on error
 
 	return flag.Args()
 }
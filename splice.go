@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+)
+
+// importSection is the import declarations of a file, parsed with
+// parser.ImportsOnly into a plain go/ast tree
+type importSection struct {
+	fileSet     *token.FileSet
+	node        *ast.File
+	first, last *ast.GenDecl // first and last import declaration, nil without imports
+}
+
+// parseImportSection parses src up to the end of its imports. Syntax errors
+// after the import section are not detected.
+func parseImportSection(src []byte) (*importSection, error) {
+	fileSet := token.NewFileSet()
+	node, err := parser.ParseFile(fileSet, "", src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	section := &importSection{fileSet: fileSet, node: node}
+	for _, decl := range node.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if section.first == nil {
+				section.first = gen
+			}
+			section.last = gen
+		}
+	}
+	return section, nil
+}
+
+func (s *importSection) offset(pos token.Pos) int {
+	return s.fileSet.Position(pos).Offset
+}
+
+// hasComments reports whether any comment sits between the package clause
+// and the end of the import section, i.e. would need moving.
+func (s *importSection) hasComments() bool {
+	end := s.last.End()
+	for _, group := range s.node.Comments {
+		if group.Pos() > s.node.Name.End() && group.Pos() < end {
+			return true
+		}
+		// a trailing comment on the line of the last import declaration
+		if group.Pos() >= end && s.fileSet.Position(group.Pos()).Line == s.fileSet.Position(end).Line {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedBlock builds the sorted import block of the section
+func (s *importSection) sortedBlock(src []byte, filePath string) ([]byte, processStats, error) {
+	var stats processStats
+	stats.module = fileLocalPrefix(filePath)
+	std := standardPackagesFor(moduleGoVersion(filePath))
+	convertedImports, err := convertASTImportsToSlice(s.node, stats.module, std)
+	if err != nil {
+		return nil, stats, err
+	}
+	if renames := adjustImports(convertedImports, src, filePath, std); len(renames) > 0 {
+		return nil, stats, errBodyRewrite
+	}
+	stats.imports = convertedImports.countImports()
+	stats.warnings = convertedImports.warnings
+	stats.violations = convertedImports.violations
+	stats.rewritten = convertedImports.rewritten
+	for _, group := range convertedImports.groups {
+		if group.countImports() > 0 {
+			stats.groups++
+		}
+	}
+	convertedImports.sortImports()
+	return convertedImports.convertImportsToGo(), stats, nil
+}
+
+// fastProcess sorts the imports without building a decorated syntax tree of
+// the whole file: it parses only the import section, splices the new block
+// into the source and gofmts the result, which is what processDST prints.
+// It reports false when the import section has comments that need moving
+// (or the file cannot be parsed), leaving the work to processDST.
+func fastProcess(src []byte, filePath string) (output []byte, stats processStats, ok bool) {
+	section, err := parseImportSection(src)
+	if err != nil {
+		return nil, stats, false
+	}
+	if section.first == nil {
+		if *addMissing {
+			// imports may need adding, after comments following the package clause
+			return nil, stats, false
+		}
+		// processDST leaves files without imports untouched as well
+		return src, stats, true
+	}
+	if section.hasComments() {
+		return nil, stats, false
+	}
+
+	rest := bytes.TrimSpace(src[section.offset(section.last.End()):])
+	if bytes.HasPrefix(rest, []byte(";")) {
+		return nil, stats, false
+	}
+
+	block, stats, err := section.sortedBlock(src, filePath)
+	if err != nil {
+		return nil, stats, false
+	}
+
+	packageEnd := section.offset(section.node.Name.End())
+	output = make([]byte, 0, len(src)+len(block))
+	output = append(output, src[:packageEnd]...)
+	output = append(output, '\n')
+	if len(block) > 0 {
+		output = append(output, '\n')
+		output = append(output, block...)
+		output = append(output, '\n')
+	}
+	if len(rest) > 0 {
+		output = append(output, '\n')
+		output = append(output, rest...)
+		output = append(output, '\n')
+	}
+	return formatSpliced(output, stats)
+}
+
+// formatSpliced gofmts the output of fastProcess, since processDST reprints
+// the code after the imports too
+func formatSpliced(output []byte, stats processStats) ([]byte, processStats, bool) {
+	formatted, err := format.Source(output)
+	if err != nil {
+		return nil, stats, false
+	}
+	return formatted, stats, true
+}
+
+// spliceImports sorts the imports of src by parsing only the import section
+// and replacing its bytes, leaving the rest of the file untouched. It works
+// on files with syntax errors after the imports. Comments inside the
+// import declarations are dropped, as in process.
+func spliceImports(src []byte, filePath string) (output []byte, stats processStats, err error) {
+	section, err := parseImportSection(src)
+	if err != nil {
+		return nil, stats, err
+	}
+	if section.first == nil {
+		return src, stats, nil
+	}
+
+	block, stats, err := section.sortedBlock(src, filePath)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	start := section.offset(section.first.Pos())
+	end := section.offset(section.last.End())
+	output = make([]byte, 0, len(src))
+	output = append(output, src[:start]...)
+	output = append(output, block...)
+	output = append(output, src[end:]...)
+	return output, stats, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFastProcess_MatchesDST(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/FFengIll/sortimport"
+
+	inputs := map[string]string{
+		"unsorted": "package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n\n\t\"github.com/x/y\"\n)\n\nfunc main() {}\n",
+		"single":   "package main\nimport \"fmt\"\n\n\nfunc main() {\n\tfmt.Println()\n}",
+		"several":  "package main\n\nimport \"os\"\nimport (\n\t\"fmt\"\n)\n\nvar _ = os.Args\n",
+		"only":     "package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n",
+		"aliases":  "package main\n\nimport (\n\t_ \"embed\"\n\t. \"fmt\"\n\tx \"github.com/x/y\"\n)\n",
+		// the code after the imports is not gofmt-clean
+		"body":      "package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\nfunc  f( )  {fmt.Println( os.Args )}\nvar x=1\n",
+		"noimports": "package main\nfunc  f( )  {\n  return\n}\n",
+	}
+	// Every file of this repository must come out the same.
+	files, _ := filepath.Glob("*.go")
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[file] = string(src)
+	}
+
+	for name, src := range inputs {
+		t.Run(name, func(t *testing.T) {
+			fast, _, ok := fastProcess([]byte(src), "")
+			if !ok {
+				t.Skip("fast path not applicable")
+			}
+			slow, _, err := processDST([]byte(src), "")
+			if err != nil {
+				t.Fatalf("processDST: %v", err)
+			}
+			if !bytes.Equal(fast, slow) {
+				t.Errorf("fast path differs from dst\nfast:\n%s\ndst:\n%s", fast, slow)
+			}
+		})
+	}
+}
+
+func TestFastProcess_FallsBackOnComments(t *testing.T) {
+	for _, src := range []string{
+		"package main\n\n// doc\nimport \"fmt\"\n",
+		"package main // c\n\nimport \"fmt\"\n",
+		"package main\n\nimport (\n\t\"fmt\" // why\n)\n",
+		"package main\n\nimport \"fmt\" // why\n",
+		"package main\n\nimport (\n\t/* a */ \"fmt\"\n)\n",
+	} {
+		if _, _, ok := fastProcess([]byte(src), ""); ok {
+			t.Errorf("expected fallback for:\n%s", src)
+		}
+	}
+	// Comments after the imports stay where they are.
+	src := "package main\n\nimport \"fmt\"\n\n// Foo is documented.\nfunc Foo() { fmt.Println() }\n"
+	if _, _, ok := fastProcess([]byte(src), ""); !ok {
+		t.Errorf("expected fast path for:\n%s", src)
+	}
+}
+
+// largeSource generates a big file with few imports, like generated code.
+func largeSource() []byte {
+	var b strings.Builder
+	b.WriteString("package big\n\nimport (\n\t\"strings\"\n\t\"fmt\"\n\t\"github.com/x/y\"\n\t\"os\"\n)\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&b, "\n// F%d does things.\nfunc F%d(s string) string {\n\tif s == \"\" {\n\t\treturn fmt.Sprint(os.Args, y.V)\n\t}\n\treturn strings.Repeat(s, %d)\n}\n", i, i, i)
+	}
+	return []byte(b.String())
+}
+
+func BenchmarkProcess_Fast(b *testing.B) {
+	src := largeSource()
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, ok := fastProcess(src, ""); !ok {
+			b.Fatal("fast path not taken")
+		}
+	}
+}
+
+func BenchmarkProcess_DST(b *testing.B) {
+	src := largeSource()
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := processDST(src, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
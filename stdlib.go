@@ -0,0 +1,62 @@
+package main
+
+//go:generate go run gen_stdlib.go
+
+import (
+	_ "embed"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// stdPackagesTable lists every public std package with the minor Go
+// release that introduced it, see gen_stdlib.go
+//
+//go:embed std_packages.txt
+var stdPackagesTable string
+
+var goMinorRe = regexp.MustCompile(`^go1\.(\d+)`)
+
+// goMinor extracts the minor release from a version such as "go1.22.3".
+func goMinor(version string) (int, bool) {
+	m := goMinorRe.FindStringSubmatch(version)
+	if m == nil {
+		return 0, false
+	}
+	minor, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return minor, true
+}
+
+// embeddedStdPackages returns the embedded std package set of the given Go
+// version. It reports false for versions newer than the table (or
+// unparseable ones), which must be loaded from the toolchain instead.
+func embeddedStdPackages(version string) (map[string]struct{}, bool) {
+	minor, ok := goMinor(version)
+	if !ok {
+		return nil, false
+	}
+
+	packages := make(map[string]struct{})
+	for _, line := range strings.Split(stdPackagesTable, "\n") {
+		if max, ok := strings.CutPrefix(line, "# max "); ok {
+			if maxMinor, ok := goMinor(max); !ok || minor > maxMinor {
+				return nil, false
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		path, since, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(since); err == nil && n <= minor {
+			packages[path] = struct{}{}
+		}
+	}
+	return packages, true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestGoMinor(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+		ok      bool
+	}{
+		{"go1.21", 21, true},
+		{"go1.22.3", 22, true},
+		{"go1.23rc1", 23, true},
+		{"devel go1.24-abcdef", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := goMinor(tt.version)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("goMinor(%q) = %d, %v, want %d, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEmbeddedStdPackages(t *testing.T) {
+	go120, ok := embeddedStdPackages("go1.20.14")
+	if !ok {
+		t.Fatal("expected embedded table for go1.20")
+	}
+	go123, ok := embeddedStdPackages("go1.23.0")
+	if !ok {
+		t.Fatal("expected embedded table for go1.23")
+	}
+	for _, pkg := range []string{"fmt", "net/http", "time/tzdata"} {
+		if _, ok := go120[pkg]; !ok {
+			t.Errorf("expected %s in go1.20 table", pkg)
+		}
+	}
+	for _, pkg := range []string{"iter", "unique"} {
+		if _, ok := go120[pkg]; ok {
+			t.Errorf("did not expect %s in go1.20 table", pkg)
+		}
+		if _, ok := go123[pkg]; !ok {
+			t.Errorf("expected %s in go1.23 table", pkg)
+		}
+	}
+
+	if _, ok := embeddedStdPackages("go1.999"); ok {
+		t.Error("expected no table for an unknown future release")
+	}
+	if _, ok := embeddedStdPackages("devel"); ok {
+		t.Error("expected no table for an unparseable version")
+	}
+}
+
+func TestEmbeddedStdPackages_MatchesToolchain(t *testing.T) {
+	// The table for the running toolchain must cover every public package
+	// the go command reports.
+	embedded, ok := embeddedStdPackages(runtime.Version())
+	if !ok {
+		t.Skip("running toolchain is newer than the embedded tables")
+	}
+	pkgs, err := packages.Load(nil, "std")
+	if err != nil {
+		t.Skipf("go command not available: %v", err)
+	}
+	for _, p := range pkgs {
+		if strings.Contains(p.PkgPath, "internal") || strings.HasPrefix(p.PkgPath, "vendor/") {
+			continue
+		}
+		if _, ok := embedded[p.PkgPath]; !ok {
+			t.Errorf("embedded table misses %s", p.PkgPath)
+		}
+	}
+}
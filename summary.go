@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// fileOutcome is what happened to a single file during a run
+type fileOutcome int
+
+const (
+	outcomeChanged fileOutcome = iota
+	outcomeUnchanged
+	outcomeSkipped // known to be sorted, see RunCache
+	outcomeFailed
+)
+
+// runSummary collects file outcomes and errors for the end-of-run report.
+// All methods are no-ops on a nil summary.
+type runSummary struct {
+	start     time.Time
+	scanned   int
+	changed   int
+	unchanged int
+	skipped   int
+	failed    int
+	failures  []error
+}
+
+// summary is the report of the current run, nil when not reporting
+var summary *runSummary
+
+func newRunSummary() *runSummary {
+	return &runSummary{start: time.Now()}
+}
+
+// record counts the outcome of one file. Files that are not sorted in check
+// mode count as changed, not as failed.
+func (s *runSummary) record(path string, outcome fileOutcome, err error) {
+	if s == nil {
+		return
+	}
+	s.scanned++
+	switch {
+	case err != nil && !errors.Is(err, errNotSorted):
+		s.failed++
+		s.failures = append(s.failures, err)
+	case outcome == outcomeChanged:
+		s.changed++
+	case outcome == outcomeSkipped:
+		s.skipped++
+	default:
+		s.unchanged++
+	}
+}
+
+// report logs every failure followed by the totals, and returns an error
+// when any file failed.
+func (s *runSummary) report() error {
+	if s == nil {
+		return nil
+	}
+	for _, err := range s.failures {
+		errorf("%v", err)
+	}
+	logger.Info("summary",
+		"scanned", s.scanned,
+		"changed", s.changed,
+		"unchanged", s.unchanged,
+		"skipped", s.skipped,
+		"failed", s.failed,
+		"elapsed", time.Since(s.start).Round(time.Millisecond),
+	)
+	if s.failed > 0 {
+		return fmt.Errorf("%d of %d files failed", s.failed, s.scanned)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessPaths_CollectsAllErrors(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	resetBoolFlag(t, write)
+	*localPrefix = "github.com/myorg/myrepo"
+	*write = true
+
+	prev := summary
+	t.Cleanup(func() { summary = prev })
+	summary = newRunSummary()
+
+	root := t.TempDir()
+	unsorted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	sorted := "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"
+	files := map[string]string{
+		"a_bad.go":  "package a\nimport (\"fmt\"\nfunc {",
+		"b_good.go": unsorted,
+		"c_bad.go":  "package a\nimport fmt",
+		"d_same.go": sorted,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(root, "missing.go")
+
+	err := processPaths([]string{root, missing}, os.Stdout)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, name := range []string{"a_bad.go", "c_bad.go", "missing.go"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to mention %s, got: %v", name, err)
+		}
+	}
+
+	// The good file after the first failure must still be processed.
+	if disk, _ := os.ReadFile(filepath.Join(root, "b_good.go")); string(disk) != sorted {
+		t.Errorf("walk stopped at the first failing file:\n%s", disk)
+	}
+
+	if summary.scanned != 5 || summary.changed != 1 || summary.unchanged != 1 || summary.failed != 3 {
+		t.Errorf("unexpected summary %+v", *summary)
+	}
+	if err := summary.report(); err == nil || !strings.Contains(err.Error(), "3 of 5 files failed") {
+		t.Errorf("report() = %v, want 3 of 5 files failed", err)
+	}
+}
+
+func TestWalkDir_UnreadableDirContinues(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	prev := summary
+	t.Cleanup(func() { summary = prev })
+	summary = newRunSummary()
+
+	root := t.TempDir()
+	locked := filepath.Join(root, "a_locked")
+	for _, name := range []string{filepath.Join("a_locked", "x.go"), "b.go"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(locked, 0755) })
+
+	err := walkDir(root)
+	if err == nil || !strings.Contains(err.Error(), "a_locked") {
+		t.Errorf("expected an error for the unreadable directory, got %v", err)
+	}
+	if summary.failed != 1 || summary.unchanged != 1 {
+		t.Errorf("walk should record the failure and go on, got %+v", *summary)
+	}
+}
+
+func TestWalkDir_MissingRoot(t *testing.T) {
+	prev := summary
+	t.Cleanup(func() { summary = prev })
+	summary = newRunSummary()
+
+	if err := walkDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if summary.failed != 1 || len(summary.failures) != 1 {
+		t.Errorf("expected the walk error to be recorded, got %+v", *summary)
+	}
+}
+
+func TestRunSummary_CheckModeIsNotFailure(t *testing.T) {
+	s := newRunSummary()
+	s.record("a.go", outcomeChanged, errNotSorted)
+	s.record("b.go", outcomeSkipped, nil)
+	if s.changed != 1 || s.skipped != 1 || s.failed != 0 {
+		t.Errorf("unexpected summary %+v", *s)
+	}
+	if err := s.report(); err != nil {
+		t.Errorf("report() = %v, want nil", err)
+	}
+}
+
+func TestRunSummary_Nil(t *testing.T) {
+	var s *runSummary
+	s.record("a.go", outcomeFailed, errors.New("boom"))
+	if err := s.report(); err != nil {
+		t.Errorf("nil summary report() = %v", err)
+	}
+}
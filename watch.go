@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// watcher polls a set of paths and re-processes go files whose content
+// changed. A change is only processed once the content has been stable
+// for one polling interval, so rapid saves are debounced.
+type watcher struct {
+	paths    []string
+	interval time.Duration
+	out      io.Writer
+
+	// hashes holds the last content hash that was processed (or written) per file
+	hashes map[string][sha256.Size]byte
+	// pending holds the hash of changed files waiting for their content to settle
+	pending map[string][sha256.Size]byte
+}
+
+// newWatcher creates a watcher for the given paths.
+func newWatcher(paths []string, interval time.Duration, out io.Writer) *watcher {
+	stripped := make([]string, 0, len(paths))
+	for _, path := range paths {
+		stripped = append(stripped, stripGoEllipsis(path))
+	}
+	return &watcher{
+		paths:    stripped,
+		interval: interval,
+		out:      out,
+		hashes:   make(map[string][sha256.Size]byte),
+		pending:  make(map[string][sha256.Size]byte),
+	}
+}
+
+// scan hashes all go files currently below the watched paths.
+func (w *watcher) scan() map[string][sha256.Size]byte {
+	current := make(map[string][sha256.Size]byte)
+	record := func(path string) error {
+		if src, err := os.ReadFile(path); err == nil {
+			current[path] = sha256.Sum256(src)
+		}
+		return nil
+	}
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			_ = walkGoFiles(path, record)
+		} else {
+			_ = record(path)
+		}
+	}
+	return current
+}
+
+// seed records the current state without processing anything.
+func (w *watcher) seed() {
+	w.hashes = w.scan()
+}
+
+// poll checks for changes and processes files whose content settled since
+// the previous poll. It returns the files that were processed.
+func (w *watcher) poll() []string {
+	current := w.scan()
+
+	for path := range w.hashes {
+		if _, ok := current[path]; !ok {
+			delete(w.hashes, path)
+			delete(w.pending, path)
+		}
+	}
+
+	var processed []string
+	for path, sum := range current {
+		if prev, ok := w.hashes[path]; ok && prev == sum {
+			delete(w.pending, path)
+			continue
+		}
+		if pending, ok := w.pending[path]; !ok || pending != sum {
+			// first sighting of this content; wait for it to settle
+			w.pending[path] = sum
+			continue
+		}
+		delete(w.pending, path)
+		w.hashes[path] = sum
+		// the declarations of the directory may have changed
+		delete(packageDecls, filepath.Dir(path))
+
+		if _, err := processFile(path, nil, w.out); err != nil {
+			logger.Error(err.Error(), "path", path)
+			continue
+		}
+		processed = append(processed, path)
+		// Remember our own write so it is not picked up as a change.
+		if src, err := os.ReadFile(path); err == nil {
+			w.hashes[path] = sha256.Sum256(src)
+		}
+	}
+	return processed
+}
+
+// run polls until ctx is cancelled.
+func (w *watcher) run(ctx context.Context) error {
+	w.seed()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWatcher_Poll(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	resetBoolFlag(t, write)
+	*localPrefix = "github.com/myorg/myrepo"
+	*write = true
+
+	root := t.TempDir()
+	file := filepath.Join(root, "sub", "a.go")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sorted := "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"
+	if err := os.WriteFile(file, []byte(sorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newWatcher([]string{root + "/..."}, 0, &bytes.Buffer{})
+	w.seed()
+	if got := w.poll(); len(got) != 0 {
+		t.Errorf("unchanged tree processed %v", got)
+	}
+
+	unsorted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	if err := os.WriteFile(file, []byte(unsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// First poll only notices the change (debounce).
+	if got := w.poll(); len(got) != 0 {
+		t.Errorf("expected change to be debounced, processed %v", got)
+	}
+	// A further save resets the debounce.
+	if err := os.WriteFile(file, []byte(unsorted+"\nvar x int\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.poll(); len(got) != 0 {
+		t.Errorf("expected rapid save to be debounced, processed %v", got)
+	}
+	if got := w.poll(); !reflect.DeepEqual(got, []string{file}) {
+		t.Errorf("processed = %v, want [%s]", got, file)
+	}
+	disk, _ := os.ReadFile(file)
+	if strings.Index(string(disk), "\"fmt\"") > strings.Index(string(disk), "\"os\"") {
+		t.Errorf("file not sorted:\n%s", disk)
+	}
+
+	// Our own write must not trigger another round.
+	for i := 0; i < 2; i++ {
+		if got := w.poll(); len(got) != 0 {
+			t.Errorf("own write was picked up as a change: %v", got)
+		}
+	}
+}
+
+func TestWatcher_NewAndDeletedFiles(t *testing.T) {
+	resetStringFlag(t, localPrefix)
+	*localPrefix = "github.com/myorg/myrepo"
+
+	root := t.TempDir()
+	w := newWatcher([]string{root}, 0, &bytes.Buffer{})
+	w.seed()
+
+	file := filepath.Join(root, "new.go")
+	if err := os.WriteFile(file, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	if got := w.poll(); !reflect.DeepEqual(got, []string{file}) {
+		t.Errorf("processed = %v, want [%s]", got, file)
+	}
+
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	if _, ok := w.hashes[file]; ok {
+		t.Error("deleted file should be forgotten")
+	}
+}